@@ -0,0 +1,45 @@
+// Command fakeapi serves a local stand-in for the public Palworld server list API.
+//
+//	go run ./cmd/fakeapi -count 120 -page-size 20
+//
+// then start the API with -api-base http://127.0.0.1:8090.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"palworld-query-api/internal/apitest"
+)
+
+func main() {
+	config := apitest.Config{}
+	var addr string
+	var file string
+	var prefix string
+	var count int
+	flag.StringVar(&addr, "addr", "127.0.0.1:8090", "listen address")
+	flag.StringVar(&file, "servers", "", "JSON file with the server list, in the upstream format")
+	flag.StringVar(&prefix, "prefix", "Palworld Server", "name prefix of generated servers")
+	flag.IntVar(&count, "count", 50, "number of servers to generate when -servers is not set")
+	flag.IntVar(&config.PageSize, "page-size", apitest.DefaultPageSize, "servers per page")
+	flag.DurationVar(&config.Latency, "latency", 0, "delay before every answer")
+	flag.Parse()
+
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := json.Unmarshal(data, &config.Servers); err != nil {
+			log.Fatalf("Error parsing %s: %v", file, err)
+		}
+	} else {
+		config.Servers = apitest.GenerateServers(prefix, count)
+	}
+
+	log.Printf("Fake server list API with %d servers listening on %s", len(config.Servers), addr)
+	log.Fatal(http.ListenAndServe(addr, apitest.NewHandler(config)))
+}
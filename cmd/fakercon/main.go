@@ -0,0 +1,103 @@
+// Command fakercon runs a fake Palworld server with RCON for local development.
+//
+//	go run ./cmd/fakercon -password secret -players "Alice:1111:76561198000000001"
+//
+// then point a server in rcon.yaml at 127.0.0.1:25575 with the same password.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"palworld-query-api/internal/rcontest"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func main() {
+	config := rcontest.Config{}
+	var players string
+	var script string
+	flag.StringVar(&config.Address, "addr", "127.0.0.1:25575", "listen address")
+	flag.StringVar(&config.Password, "password", "admin", "admin password")
+	flag.StringVar(&config.Name, "name", "Fake Palworld Server", "server name reported by Info")
+	flag.StringVar(&config.Version, "version", "v0.1.5.1", "server version reported by Info")
+	flag.StringVar(&players, "players", "", "players online at start, as name:playeruid:steamid separated by commas")
+	flag.StringVar(&script, "script", "", "JSON file with scripted joins and leaves, e.g. [{\"at\": \"10s\", \"join\": {...}}, {\"at\": \"20s\", \"leave\": \"7656...\"}]")
+	flag.DurationVar(&config.Latency, "latency", 0, "delay before every answer")
+	flag.BoolVar(&config.Faults.AuthFailure, "auth-failure", false, "refuse every password")
+	flag.BoolVar(&config.Faults.Truncate, "truncate", false, "send half of each answer and close the connection")
+	flag.BoolVar(&config.Faults.NullBytes, "null-bytes", false, "pad answers with null bytes")
+	flag.BoolVar(&config.Faults.Hang, "hang", false, "never answer commands")
+	flag.Parse()
+
+	var err error
+	if config.Players, err = parsePlayers(players); err != nil {
+		log.Fatal(err)
+	}
+	if script != "" {
+		if config.Script, err = readScript(script); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	server, err := rcontest.NewServer(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Fake Palworld RCON server listening on %s with %d players and %d scripted steps", server.Addr(), len(config.Players), len(config.Script))
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	server.Close()
+}
+
+// parsePlayers reads players given as name:playeruid:steamid, separated by commas.
+func parsePlayers(value string) ([]rcontest.Player, error) {
+	var players []rcontest.Player
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("player %q must look like name:playeruid:steamid", entry)
+		}
+		players = append(players, rcontest.Player{Name: parts[0], PlayerUID: parts[1], SteamID: parts[2]})
+	}
+	return players, nil
+}
+
+// scriptStep is a rcontest.Step with its time written as a duration string.
+type scriptStep struct {
+	At    string           `json:"at"`
+	Join  *rcontest.Player `json:"join,omitempty"`
+	Leave string           `json:"leave,omitempty"`
+}
+
+func readScript(path string) ([]rcontest.Step, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var steps []scriptStep
+	if err := json.Unmarshal(data, &steps); err != nil {
+		return nil, fmt.Errorf("error parsing script: %v", err)
+	}
+
+	script := make([]rcontest.Step, 0, len(steps))
+	for i, step := range steps {
+		at, err := time.ParseDuration(step.At)
+		if err != nil {
+			return nil, fmt.Errorf("script step %d: invalid at %q: %v", i, step.At, err)
+		}
+		script = append(script, rcontest.Step{At: at, Join: step.Join, Leave: step.Leave})
+	}
+	return script, nil
+}
@@ -1,32 +1,112 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"palworld-query-api/internal/config"
 	"palworld-query-api/internal/routes"
 )
 
 func main() {
+	// Read the configuration from the environment and flags
+	config.Load()
+
+	// Subcommands run instead of the server
+	if flag.Arg(0) == "validate-config" {
+		os.Exit(validateConfig(flag.Args()[1:]))
+	}
+
+	// Generate rcon.yaml from CONFIG_JSON, after the subcommands so they check the file as it is
+	config.ApplyConfigJSON()
+
 	port := fmt.Sprintf(":%s", config.Config.Port)
 	routeRoot := config.Routes.Index
     routeHealth := config.Routes.Health
-    routRcon := config.Routes.Rcon
-    routeApi := config.Routes.Api
 
-	// Register healthz route
-	http.HandleFunc(routeHealth, routes.HealthHandler)
+	// Load the API keys before anything is served
+	if err := config.LoadAPIKeys(); err != nil {
+		log.Fatalf("Error loading API keys: %v", err)
+	}
+
+	// Load rcon.yaml once and reload it when it changes. Mistakes are reported right away
+	// instead of on the first request, and stop the server unless it runs lenient
+	if err := config.ServerConfigs.Start(config.Config.CliConfig); err != nil {
+		if !config.Config.Lenient {
+			log.Fatalf("Error in server config, fix it or start with -lenient: %v", err)
+		}
+		log.Printf("Error in server config: %v", err)
+	}
+	defer config.ServerConfigs.Close()
+	config.ServerConfigs.OnChange(config.Pool.Retain)
+	config.ServerConfigs.OnChange(config.Breakers.Retain)
+	config.ServerConfigs.OnChange(config.Snapshots.Retain)
+
+	// handle registers a route with request metrics labeled by its pattern,
+	// open to API keys with at least the given role
+	handle := func(route string, role string, handler http.HandlerFunc) {
+		http.HandleFunc(route, routes.Instrument(route, routes.RequireRole(role, handler)))
+	}
+
+	// Register healthz route, it stays public for container health checks
+	http.HandleFunc(routeHealth, routes.Instrument(routeHealth, routes.HealthHandler))
+
+	// Register server status routes and their legacy aliases
+	handle(config.Routes.Servers, config.RoleViewer, routes.ServersHandler)
+	handle(config.Routes.Server, config.RoleViewer, routes.ServerHandler)
+	handle(config.Routes.Rcon, config.RoleViewer, routes.ServersHandler)
+	handle(config.Routes.RconServer, config.RoleViewer, routes.ServerHandler)
+
+	// Register server group summary route
+	handle(config.Routes.Group, config.RoleViewer, routes.GroupHandler)
 
-	// Register rcon route
-	http.HandleFunc(routRcon, routes.RconHandler)
+	// Register player events route
+	handle(config.Routes.RconEvents, config.RoleViewer, routes.EventsHandler)
 
-	// Register api route
-	http.HandleFunc(routeApi, routes.ApiHandler)
+	// Register live stream routes
+	handle(config.Routes.RconStream, config.RoleViewer, routes.StreamHandler)
+	handle(config.Routes.Stream, config.RoleViewer, routes.StreamAllHandler)
+
+	// Register player history routes
+	handle(config.Routes.RconPlayersHistory, config.RoleViewer, routes.PlayersHistoryHandler)
+	handle(config.Routes.Player, config.RoleViewer, routes.PlayerHandler)
+
+	// Register admin rcon command routes, moderators can't stop the server
+	handle(config.Routes.RconBroadcast, config.RoleModerator, routes.BroadcastHandler)
+	handle(config.Routes.RconKick, config.RoleModerator, routes.KickHandler)
+	handle(config.Routes.RconBan, config.RoleModerator, routes.BanHandler)
+	handle(config.Routes.RconSave, config.RoleModerator, routes.SaveHandler)
+	handle(config.Routes.RconShutdown, config.RoleAdmin, routes.ShutdownHandler)
+	handle(config.Routes.RconExit, config.RoleAdmin, routes.ExitHandler)
+
+	// Register prometheus metrics route
+	http.Handle(config.Routes.Metrics, routes.RequireRole(config.RoleViewer, routes.MetricsHandler()))
+
+	// Register public server list search and its legacy alias
+	handle(config.Routes.PublicSearch, config.RoleViewer, routes.ApiHandler)
+	handle(config.Routes.Api, config.RoleViewer, routes.ApiHandler)
 
 	// Register root route to list available routes
-	http.HandleFunc(routeRoot, routes.IndexHandler)
+	handle(routeRoot, config.RoleViewer, routes.IndexHandler)
+
+	// Open the player history store, the API keeps working without it
+	history, err := config.OpenHistory(config.Config.DataPath)
+	if err != nil {
+		log.Printf("Player history disabled: %v", err)
+	} else {
+		config.History = history
+		defer history.Close()
+	}
+
+	// Start delivering webhook notifications
+	config.Notifier.Start()
+
+	// Start polling the servers in the background
+	config.StartPoller(context.Background())
 
 	log.Printf("server listening on port %s\n", port)
-	log.Fatal(http.ListenAndServe(port, nil))
+	log.Fatal(http.ListenAndServe(port, routes.JSONErrors(http.DefaultServeMux)))
 }
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"palworld-query-api/internal/config"
+)
+
+// validateConfig checks rcon.yaml, at the given path or -cli-config, prints every problem
+// and returns the exit code: 1 if the config has errors, 0 otherwise.
+func validateConfig(args []string) int {
+	path := config.Config.CliConfig
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	problems := config.ValidateConfig(data)
+	errors := 0
+	for _, problem := range problems {
+		fmt.Println(problem.Format(path))
+		if problem.Fatal {
+			errors++
+		}
+	}
+	if errors > 0 {
+		fmt.Printf("%s: %d errors, %d warnings\n", path, errors, len(problems)-errors)
+		return 1
+	}
+	fmt.Printf("%s: OK, %d warnings\n", path, len(problems))
+	return 0
+}
@@ -3,8 +3,23 @@ module palworld-query-api
 go 1.22
 
 require (
-	github.com/fsnotify/fsnotify v1.7.0 // indirect
-	github.com/gorcon/rcon v1.3.5 // indirect
-	golang.org/x/sys v0.4.0 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
+	github.com/fsnotify/fsnotify v1.7.0
+	github.com/gorcon/rcon v1.3.5
+	github.com/gorilla/websocket v1.5.3
+	github.com/prometheus/client_golang v1.19.1
+	github.com/prometheus/client_model v0.5.0
+	go.etcd.io/bbolt v1.3.10
+	golang.org/x/sync v0.5.0
+	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/prometheus/common v0.48.0 // indirect
+	github.com/prometheus/procfs v0.12.0 // indirect
+	golang.org/x/sys v0.17.0 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
 )
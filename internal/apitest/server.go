@@ -0,0 +1,171 @@
+// Package apitest runs a local stand-in for the public Palworld server list API,
+// for exercising /v1/public/search without reaching api.palworldgame.com.
+package apitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"palworld-query-api/internal/routes"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Paths served by the mock, the same as the real API's
+const (
+	SearchPath = "/server/search"
+	ListPath   = "/server/list"
+)
+
+// DefaultPageSize is the page size when Config.PageSize is not set.
+const DefaultPageSize = 20
+
+// Config describes the mock. Servers are in the upstream format, the one routes decodes.
+type Config struct {
+	Servers  []routes.Server
+	PageSize int           // servers per page, DefaultPageSize by default
+	Latency  time.Duration // delay before every answer
+}
+
+// Handler serves the search and list endpoints over a fixed server list.
+type Handler struct {
+	mu       sync.Mutex
+	config   Config
+	requests int
+}
+
+// NewHandler returns the mock as an http.Handler, for serving it on any listener.
+func NewHandler(config Config) *Handler {
+	if config.PageSize <= 0 {
+		config.PageSize = DefaultPageSize
+	}
+	return &Handler{config: config}
+}
+
+// NewServer starts the mock on a free local port. Close the returned server when done,
+// and point ApiConfig.Base at its URL.
+func NewServer(config Config) (*httptest.Server, *Handler) {
+	handler := NewHandler(config)
+	return httptest.NewServer(handler), handler
+}
+
+// Requests is the number of requests served so far.
+func (h *Handler) Requests() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.requests
+}
+
+// SetServers replaces the server list.
+func (h *Handler) SetServers(servers []routes.Server) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.config.Servers = servers
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	h.requests++
+	config := h.config
+	h.mu.Unlock()
+
+	if config.Latency > 0 {
+		time.Sleep(config.Latency)
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	page := 1
+	if value := query.Get("page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			http.Error(w, "invalid page", http.StatusBadRequest)
+			return
+		}
+		page = parsed
+	}
+
+	var servers []routes.Server
+	switch r.URL.Path {
+	case SearchPath:
+		q := strings.ToLower(query.Get("q"))
+		for _, server := range config.Servers {
+			if strings.Contains(strings.ToLower(server.Name), q) {
+				servers = append(servers, server)
+			}
+		}
+	case ListPath:
+		servers = config.Servers
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	// Slice out the page and link the next one relative to the API base, like upstream
+	start := (page - 1) * config.PageSize
+	if start > len(servers) {
+		start = len(servers)
+	}
+	end := start + config.PageSize
+	if end > len(servers) {
+		end = len(servers)
+	}
+	response := routes.ServerListResponse{
+		CurrentPage: page,
+		PageSize:    config.PageSize,
+		SortType:    "current_player_num",
+		ServerType:  "normal",
+		Region:      "all",
+		IsNextPage:  end < len(servers),
+		ServerList:  append([]routes.Server{}, servers[start:end]...),
+	}
+	if response.IsNextPage {
+		next := url.Values{}
+		for key, values := range query {
+			next[key] = values
+		}
+		next.Set("page", strconv.Itoa(page+1))
+		response.NextPageURL = r.URL.Path + "?" + next.Encode()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GenerateServers makes count servers named "<prefix> 1" to "<prefix> <count>" with
+// varied regions, versions and player counts.
+func GenerateServers(prefix string, count int) []routes.Server {
+	regions := []string{"us", "eu", "asia"}
+	versions := []string{"v0.1.5.0", "v0.1.5.1", "v0.2.0.6"}
+	servers := make([]routes.Server, 0, count)
+	for i := 1; i <= count; i++ {
+		servers = append(servers, routes.Server{
+			ServerID:       fmt.Sprintf("%032x", i),
+			Namespace:      "default",
+			Type:           "normal",
+			Region:         regions[i%len(regions)],
+			Name:           fmt.Sprintf("%s %d", prefix, i),
+			MapName:        "MainWorld5",
+			Description:    fmt.Sprintf("Test server number %d", i),
+			Address:        fmt.Sprintf("203.0.113.%d", i%250+1),
+			Port:           8211,
+			IsPassword:     i%4 == 0,
+			Version:        versions[i%len(versions)],
+			CreatedAt:      1705000000 + int64(i),
+			UpdateAt:       1705100000 + int64(i),
+			WorldGUID:      fmt.Sprintf("%032X", i),
+			CurrentPlayers: i % 32,
+			MaxPlayers:     32,
+			Days:           i * 3,
+			ServerTime:     i * 60,
+		})
+	}
+	return servers
+}
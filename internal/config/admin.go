@@ -0,0 +1,190 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CommandResult is the typed result of an admin RCON command.
+type CommandResult struct {
+	Command string `json:"command"`
+	Success bool   `json:"success"`
+	SteamID string `json:"steamId,omitempty"`
+	Seconds int    `json:"seconds,omitempty"`
+	Message string `json:"message"`
+}
+
+// Limits for admin command arguments
+const (
+	MaxShutdownSeconds = 3600
+	MaxMessageLength   = 256
+)
+
+// ErrInvalidArgument is returned when an admin command argument fails validation.
+var ErrInvalidArgument = errors.New("invalid argument")
+
+// steamIDPattern matches a SteamID64, optionally with the "steam_" prefix newer servers report.
+var steamIDPattern = regexp.MustCompile(`^(steam_)?7656119\d{10}$`)
+
+// checks if the given string is a valid Steam ID.
+func IsValidSteamID(steamID string) bool {
+	return steamIDPattern.MatchString(steamID)
+}
+
+// validateMessage checks a broadcast or shutdown message.
+func validateMessage(message string) (string, error) {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return "", fmt.Errorf("%w: message is required", ErrInvalidArgument)
+	}
+	if len(message) > MaxMessageLength {
+		return "", fmt.Errorf("%w: message is longer than %d characters", ErrInvalidArgument, MaxMessageLength)
+	}
+	if strings.ContainsAny(message, "\r\n\x00") {
+		return "", fmt.Errorf("%w: message must be a single line", ErrInvalidArgument)
+	}
+	return message, nil
+}
+
+func validateSteamID(steamID string) error {
+	if !IsValidSteamID(steamID) {
+		return fmt.Errorf("%w: '%s' is not a valid Steam ID", ErrInvalidArgument, steamID)
+	}
+	return nil
+}
+
+// Broadcast sends a message to every player on the server.
+func Broadcast(configServer ConfigServer, message string) (*CommandResult, error) {
+	message, err := validateMessage(message)
+	if err != nil {
+		return nil, err
+	}
+	backend, err := NewBackend(configServer)
+	if err != nil {
+		return nil, err
+	}
+	return backend.Broadcast(message)
+}
+
+// KickPlayer kicks the player with the given Steam ID.
+func KickPlayer(configServer ConfigServer, steamID string) (*CommandResult, error) {
+	if err := validateSteamID(steamID); err != nil {
+		return nil, err
+	}
+	backend, err := NewBackend(configServer)
+	if err != nil {
+		return nil, err
+	}
+	return backend.KickPlayer(steamID)
+}
+
+// BanPlayer bans the player with the given Steam ID.
+func BanPlayer(configServer ConfigServer, steamID string) (*CommandResult, error) {
+	if err := validateSteamID(steamID); err != nil {
+		return nil, err
+	}
+	backend, err := NewBackend(configServer)
+	if err != nil {
+		return nil, err
+	}
+	return backend.BanPlayer(steamID)
+}
+
+// Save saves the world data.
+func Save(configServer ConfigServer) (*CommandResult, error) {
+	backend, err := NewBackend(configServer)
+	if err != nil {
+		return nil, err
+	}
+	return backend.Save()
+}
+
+// Shutdown shuts the server down after the given number of seconds, showing message to the players.
+func Shutdown(configServer ConfigServer, seconds int, message string) (*CommandResult, error) {
+	if seconds < 1 || seconds > MaxShutdownSeconds {
+		return nil, fmt.Errorf("%w: seconds must be between 1 and %d", ErrInvalidArgument, MaxShutdownSeconds)
+	}
+	message, err := validateMessage(message)
+	if err != nil {
+		return nil, err
+	}
+	backend, err := NewBackend(configServer)
+	if err != nil {
+		return nil, err
+	}
+	return backend.Shutdown(seconds, message)
+}
+
+// DoExit stops the server immediately.
+func DoExit(configServer ConfigServer) (*CommandResult, error) {
+	backend, err := NewBackend(configServer)
+	if err != nil {
+		return nil, err
+	}
+	return backend.DoExit()
+}
+
+// runAdminCommand sends the command over RCON and wraps the response into a CommandResult.
+func (b *rconBackend) runAdminCommand(name string, command string) (*CommandResult, error) {
+	response, err := sendCommand(b.server, command)
+	if err != nil {
+		return nil, err
+	}
+	message := cleanResponse(response)
+	return &CommandResult{
+		Command: name,
+		Success: !strings.HasPrefix(strings.ToLower(message), "failed"),
+		Message: message,
+	}, nil
+}
+
+// cleanResponse strips null bytes and surrounding whitespace from an RCON response.
+func cleanResponse(response string) string {
+	return strings.TrimSpace(strings.ReplaceAll(response, "\u0000", ""))
+}
+
+// rconMessage prepares a message for RCON, which splits command arguments on spaces.
+func rconMessage(message string) string {
+	return strings.ReplaceAll(message, " ", "_")
+}
+
+func (b *rconBackend) Broadcast(message string) (*CommandResult, error) {
+	return b.runAdminCommand(Rcon.Command.Broadcast, Rcon.Command.Broadcast+" "+rconMessage(message))
+}
+
+func (b *rconBackend) KickPlayer(steamID string) (*CommandResult, error) {
+	result, err := b.runAdminCommand(Rcon.Command.KickPlayer, Rcon.Command.KickPlayer+" "+steamID)
+	if err != nil {
+		return nil, err
+	}
+	result.SteamID = steamID
+	return result, nil
+}
+
+func (b *rconBackend) BanPlayer(steamID string) (*CommandResult, error) {
+	result, err := b.runAdminCommand(Rcon.Command.BanPlayer, Rcon.Command.BanPlayer+" "+steamID)
+	if err != nil {
+		return nil, err
+	}
+	result.SteamID = steamID
+	return result, nil
+}
+
+func (b *rconBackend) Save() (*CommandResult, error) {
+	return b.runAdminCommand(Rcon.Command.Save, Rcon.Command.Save)
+}
+
+func (b *rconBackend) Shutdown(seconds int, message string) (*CommandResult, error) {
+	result, err := b.runAdminCommand(Rcon.Command.Shutdown, fmt.Sprintf("%s %d %s", Rcon.Command.Shutdown, seconds, rconMessage(message)))
+	if err != nil {
+		return nil, err
+	}
+	result.Seconds = seconds
+	return result, nil
+}
+
+func (b *rconBackend) DoExit() (*CommandResult, error) {
+	return b.runAdminCommand(Rcon.Command.DoExit, Rcon.Command.DoExit)
+}
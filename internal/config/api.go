@@ -1,14 +1,22 @@
 package config
 
+import "time"
+
 // API constants
 var ApiConfig = struct {
 	Base string
 	Search string
 	List string
+	Timeout time.Duration
+	CacheTTL time.Duration
+	MaxPages int
 }{
 	Base: "https://api.palworldgame.com",
 	Search: "/server/search", // query by name
 	List: "/server/list", // paginated list
+	Timeout: 10 * time.Second, // per page request
+	CacheTTL: time.Minute, // how long search results are reused, 0 to disable
+	MaxPages: 10, // pages fetched per search, 0 for no limit
 }
 
 var HtmlDetailsTemplate =
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Roles, each one can do everything the ones before it can
+const (
+	RoleViewer    = "viewer"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var roleLevels = map[string]int{
+	RoleViewer:    1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// APIKey is a credential with a role, optionally limited to some servers.
+type APIKey struct {
+	Name    string   `yaml:"name"`
+	Key     string   `yaml:"key"`
+	Role    string   `yaml:"role"`
+	Servers []string `yaml:"servers"` // all servers when empty
+}
+
+type authFile struct {
+	Keys []APIKey `yaml:"keys"`
+}
+
+// APIKeys are the keys accepted by the auth middleware. Auth is off when there are none.
+var APIKeys []APIKey
+
+// Allows reports whether the key's role is at least role.
+func (k *APIKey) Allows(role string) bool {
+	return roleLevels[k.Role] >= roleLevels[role]
+}
+
+// CanAccess reports whether the key may see the named server.
+func (k *APIKey) CanAccess(server string) bool {
+	if len(k.Servers) == 0 {
+		return true
+	}
+	for _, allowed := range k.Servers {
+		if allowed == server {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthEnabled reports whether any API key is configured.
+func AuthEnabled() bool {
+	return len(APIKeys) > 0
+}
+
+// FindAPIKey returns the configured key matching the given secret, or nil.
+func FindAPIKey(secret string) *APIKey {
+	if secret == "" {
+		return nil
+	}
+	var found *APIKey
+	// Compare against every key so the timing does not reveal which one matched
+	for i := range APIKeys {
+		if subtle.ConstantTimeCompare([]byte(APIKeys[i].Key), []byte(secret)) == 1 {
+			found = &APIKeys[i]
+		}
+	}
+	return found
+}
+
+// LoadAPIKeys collects the keys from the auth config file, the API_KEYS setting and the admin token.
+func LoadAPIKeys() error {
+	var keys []APIKey
+
+	if _, err := os.Stat(Config.AuthConfig); err == nil {
+		data, err := ioutil.ReadFile(Config.AuthConfig)
+		if err != nil {
+			return fmt.Errorf("error reading auth config: %v", err)
+		}
+		var file authFile
+		if err := yaml.UnmarshalStrict(data, &file); err != nil {
+			return fmt.Errorf("error parsing auth config: %v", err)
+		}
+		keys = append(keys, file.Keys...)
+	}
+
+	envKeys, err := parseAPIKeys(Config.ApiKeys)
+	if err != nil {
+		return err
+	}
+	keys = append(keys, envKeys...)
+
+	if Config.AdminToken != "" {
+		keys = append(keys, APIKey{Name: "admin-token", Key: Config.AdminToken, Role: RoleAdmin})
+	}
+
+	for i, key := range keys {
+		if key.Key == "" {
+			return fmt.Errorf("API key %d (%s) has no key", i, key.Name)
+		}
+		if _, ok := roleLevels[key.Role]; !ok {
+			return fmt.Errorf("API key %d (%s) has unknown role '%s'", i, key.Name, key.Role)
+		}
+	}
+
+	APIKeys = keys
+	if AuthEnabled() {
+		log.Printf("Loaded %d API keys", len(keys))
+	} else {
+		log.Println("No API keys configured, read-only routes are public and admin commands are disabled")
+	}
+	return nil
+}
+
+// parseAPIKeys reads keys in the form key:role[:server1|server2], separated by commas.
+func parseAPIKeys(value string) ([]APIKey, error) {
+	var keys []APIKey
+	for i, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("API key %d must look like key:role[:server1|server2]", i)
+		}
+		key := APIKey{Name: fmt.Sprintf("env-%d", i), Key: parts[0], Role: parts[1]}
+		if len(parts) == 3 {
+			key.Servers = strings.Split(parts[2], "|")
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
@@ -0,0 +1,32 @@
+package config
+
+import "fmt"
+
+// Backend types for the `type` key in rcon.yaml
+const (
+	BackendRcon = "rcon"
+	BackendRest = "rest"
+)
+
+// Backend is a protocol for reading a server's status and running admin commands on it.
+type Backend interface {
+	ServerInfo() (*ServerInfo, error)
+	Broadcast(message string) (*CommandResult, error)
+	KickPlayer(steamID string) (*CommandResult, error)
+	BanPlayer(steamID string) (*CommandResult, error)
+	Save() (*CommandResult, error)
+	Shutdown(seconds int, message string) (*CommandResult, error)
+	DoExit() (*CommandResult, error)
+}
+
+// NewBackend returns the backend selected by the server's type, RCON by default.
+func NewBackend(configServer ConfigServer) (Backend, error) {
+	switch configServer.Type {
+	case "", BackendRcon:
+		return &rconBackend{server: configServer}, nil
+	case BackendRest:
+		return &restBackend{server: configServer}, nil
+	default:
+		return nil, &ServerError{Code: ErrCodeConfig, Server: configServer.Name, Err: fmt.Errorf("unsupported server type '%s'", configServer.Type)}
+	}
+}
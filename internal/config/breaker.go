@@ -0,0 +1,171 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"    // commands go through
+	BreakerOpen     = "open"      // commands fail fast until the cool-down is over
+	BreakerHalfOpen = "half_open" // one probe command decides whether to close again
+)
+
+// BreakerStates lists every state, in the order exported on /metrics.
+var BreakerStates = []string{BreakerClosed, BreakerOpen, BreakerHalfOpen}
+
+// BreakerState is a snapshot of one server's circuit breaker.
+type BreakerState struct {
+	State    string     `json:"state"`
+	Failures int        `json:"failures"`
+	OpenedAt *time.Time `json:"openedAt,omitempty"`
+	RetryAt  *time.Time `json:"retryAt,omitempty"`
+}
+
+// circuitBreaker stops talking to a server after Threshold failures in a row and
+// lets one probe through after the cool-down.
+type circuitBreaker struct {
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	cooldown time.Duration
+	probing  bool
+}
+
+// BreakerRegistry holds the circuit breaker of every server.
+type BreakerRegistry struct {
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+// Breakers is the shared registry used by the RCON and REST backends.
+var Breakers = &BreakerRegistry{breakers: make(map[string]*circuitBreaker)}
+
+func (r *BreakerRegistry) get(server string) *circuitBreaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.breakers[server]
+	if !ok {
+		b = &circuitBreaker{state: BreakerClosed}
+		r.breakers[server] = b
+	}
+	return b
+}
+
+// State returns the breaker state of the named server.
+func (r *BreakerRegistry) State(server string) BreakerState {
+	return r.get(server).snapshot()
+}
+
+// All returns the breaker state of every server that has been queried.
+func (r *BreakerRegistry) All() map[string]BreakerState {
+	r.mu.Lock()
+	breakers := make(map[string]*circuitBreaker, len(r.breakers))
+	for name, b := range r.breakers {
+		breakers[name] = b
+	}
+	r.mu.Unlock()
+
+	states := make(map[string]BreakerState, len(breakers))
+	for name, b := range breakers {
+		states[name] = b.snapshot()
+	}
+	return states
+}
+
+// Retain drops breakers of servers that are no longer configured.
+func (r *BreakerRegistry) Retain(servers map[string]ConfigServer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name := range r.breakers {
+		if _, ok := servers[name]; !ok {
+			delete(r.breakers, name)
+		}
+	}
+}
+
+// withBreaker runs command unless the server's breaker is open, and feeds the outcome back.
+// Only failures that say the server is unhealthy count; refused credentials and config
+// mistakes don't.
+func withBreaker(configServer ConfigServer, command func() error) error {
+	threshold := configServer.BreakerThresholdValue()
+	if threshold == 0 {
+		return command()
+	}
+
+	b := Breakers.get(configServer.Name)
+	if err := b.allow(configServer); err != nil {
+		return err
+	}
+	err := command()
+	unhealthy := err != nil && AsServerError(configServer.Name, err).Retryable()
+	b.record(configServer.Name, unhealthy, threshold, configServer.BreakerCooldownDuration())
+	return err
+}
+
+// allow decides whether a command may be sent now.
+func (b *circuitBreaker) allow(configServer ConfigServer) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		wait := b.cooldown - time.Since(b.openedAt)
+		if wait > 0 {
+			return &ServerError{Code: ErrCodeCircuitOpen, Server: configServer.Name,
+				Err: fmt.Errorf("circuit breaker is open after %d failures, next attempt in %s", b.failures, wait.Round(time.Second))}
+		}
+		log.Printf("Circuit breaker of %s is half-open, probing", configServer.Name)
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return nil
+	case BreakerHalfOpen:
+		if b.probing {
+			return &ServerError{Code: ErrCodeCircuitOpen, Server: configServer.Name,
+				Err: fmt.Errorf("circuit breaker is half-open, waiting for the probe")}
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// record moves the breaker according to the outcome of a command.
+func (b *circuitBreaker) record(server string, unhealthy bool, threshold int, cooldown time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if !unhealthy {
+		if b.state != BreakerClosed {
+			log.Printf("Circuit breaker of %s is closed again", server)
+		}
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || (b.state == BreakerClosed && b.failures >= threshold) {
+		log.Printf("Circuit breaker of %s is open after %d failures, cooling down for %s", server, b.failures, cooldown)
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+		b.cooldown = cooldown
+	}
+}
+
+func (b *circuitBreaker) snapshot() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := BreakerState{State: b.state, Failures: b.failures}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		retryAt := b.openedAt.Add(b.cooldown)
+		state.OpenedAt = &openedAt
+		state.RetryAt = &retryAt
+	}
+	return state
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var (
+	errUnreachable = &ServerError{Code: ErrCodeDial, Err: errors.New("connection refused")}
+	errRefused     = &ServerError{Code: ErrCodeAuth, Err: errors.New("authentication failed")}
+)
+
+// breakerStep is one command sent through a server's circuit breaker.
+type breakerStep struct {
+	wait   time.Duration // before sending
+	result error         // what the command returns if it runs
+	ran    bool          // whether the breaker let it run
+	state  string        // breaker state afterwards
+}
+
+// runBreakerSteps sends the steps through the breaker of configServer, which starts closed.
+func runBreakerSteps(t *testing.T, configServer ConfigServer, steps []breakerStep) {
+	t.Helper()
+	Breakers.Retain(nil)
+	t.Cleanup(func() { Breakers.Retain(nil) })
+	for i, step := range steps {
+		time.Sleep(step.wait)
+		ran := false
+		err := withBreaker(configServer, func() error {
+			ran = true
+			return step.result
+		})
+		if ran != step.ran {
+			t.Fatalf("step %d: ran = %v, want %v", i+1, ran, step.ran)
+		}
+		if !ran && AsServerError(configServer.Name, err).Code != ErrCodeCircuitOpen {
+			t.Fatalf("step %d: error %v, want %s", i+1, err, ErrCodeCircuitOpen)
+		}
+		if state := Breakers.State(configServer.Name); state.State != step.state {
+			t.Fatalf("step %d: state %s with %d failures, want %s", i+1, state.State, state.Failures, step.state)
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	three, one, zero := 3, 1, 0
+	cooldown := 30 * time.Millisecond
+	tests := []struct {
+		name   string
+		server ConfigServer
+		steps  []breakerStep
+	}{
+		{
+			"opens after the threshold",
+			ConfigServer{BreakerThreshold: &three, BreakerCooldown: "30ms"},
+			[]breakerStep{
+				{result: errUnreachable, ran: true, state: BreakerClosed},
+				{result: errUnreachable, ran: true, state: BreakerClosed},
+				{result: errUnreachable, ran: true, state: BreakerOpen},
+				{result: nil, ran: false, state: BreakerOpen},
+			},
+		},
+		{
+			"success resets the count",
+			ConfigServer{BreakerThreshold: &three, BreakerCooldown: "30ms"},
+			[]breakerStep{
+				{result: errUnreachable, ran: true, state: BreakerClosed},
+				{result: errUnreachable, ran: true, state: BreakerClosed},
+				{result: nil, ran: true, state: BreakerClosed},
+				{result: errUnreachable, ran: true, state: BreakerClosed},
+				{result: errUnreachable, ran: true, state: BreakerClosed},
+			},
+		},
+		{
+			"refused credentials don't count",
+			ConfigServer{BreakerThreshold: &one, BreakerCooldown: "30ms"},
+			[]breakerStep{
+				{result: errRefused, ran: true, state: BreakerClosed},
+				{result: errRefused, ran: true, state: BreakerClosed},
+			},
+		},
+		{
+			"successful probe closes",
+			ConfigServer{BreakerThreshold: &one, BreakerCooldown: "30ms"},
+			[]breakerStep{
+				{result: errUnreachable, ran: true, state: BreakerOpen},
+				{result: nil, ran: false, state: BreakerOpen},
+				{wait: 2 * cooldown, result: nil, ran: true, state: BreakerClosed},
+				{result: nil, ran: true, state: BreakerClosed},
+			},
+		},
+		{
+			"failed probe opens again",
+			ConfigServer{BreakerThreshold: &one, BreakerCooldown: "30ms"},
+			[]breakerStep{
+				{result: errUnreachable, ran: true, state: BreakerOpen},
+				{wait: 2 * cooldown, result: errUnreachable, ran: true, state: BreakerOpen},
+				{result: nil, ran: false, state: BreakerOpen},
+			},
+		},
+		{
+			"zero threshold disables the breaker",
+			ConfigServer{BreakerThreshold: &zero},
+			[]breakerStep{
+				{result: errUnreachable, ran: true, state: BreakerClosed},
+				{result: errUnreachable, ran: true, state: BreakerClosed},
+			},
+		},
+		{
+			"global settings apply without overrides",
+			ConfigServer{},
+			[]breakerStep{
+				{result: errUnreachable, ran: true, state: BreakerClosed},
+				{result: errUnreachable, ran: true, state: BreakerOpen},
+				{wait: 2 * cooldown, result: nil, ran: true, state: BreakerClosed},
+			},
+		},
+	}
+
+	saved := Config
+	Config.BreakerThreshold, Config.BreakerCooldown = 2, cooldown
+	t.Cleanup(func() { Config = saved })
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.server.Name = "breaker " + test.name
+			runBreakerSteps(t, test.server, test.steps)
+		})
+	}
+}
+
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	one := 1
+	configServer := ConfigServer{Name: "breaker probe", BreakerThreshold: &one, BreakerCooldown: "20ms"}
+	runBreakerSteps(t, configServer, []breakerStep{{result: errUnreachable, ran: true, state: BreakerOpen}})
+	time.Sleep(40 * time.Millisecond)
+
+	// While the probe runs, other commands fail fast
+	var during error
+	duringRan := false
+	err := withBreaker(configServer, func() error {
+		if state := Breakers.State(configServer.Name); state.State != BreakerHalfOpen {
+			t.Errorf("state during the probe is %s, want %s", state.State, BreakerHalfOpen)
+		}
+		during = withBreaker(configServer, func() error {
+			duringRan = true
+			return nil
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if duringRan || AsServerError(configServer.Name, during).Code != ErrCodeCircuitOpen {
+		t.Errorf("command during the probe ran = %v with %v, want it refused", duringRan, during)
+	}
+
+	state := Breakers.State(configServer.Name)
+	if state.State != BreakerClosed || state.Failures != 0 || state.OpenedAt != nil || state.RetryAt != nil {
+		t.Errorf("after the probe: %+v, want closed", state)
+	}
+}
+
+func TestCircuitBreakerRetryAt(t *testing.T) {
+	one := 1
+	configServer := ConfigServer{Name: "breaker retry at", BreakerThreshold: &one, BreakerCooldown: "1m"}
+	before := time.Now()
+	runBreakerSteps(t, configServer, []breakerStep{{result: errUnreachable, ran: true, state: BreakerOpen}})
+
+	state := Breakers.State(configServer.Name)
+	if state.OpenedAt == nil || state.RetryAt == nil || state.OpenedAt.Before(before) {
+		t.Fatalf("open breaker %+v has no opening time", state)
+	}
+	if got := state.RetryAt.Sub(*state.OpenedAt); got != time.Minute {
+		t.Errorf("retry %s after opening, want the server's cool-down of 1m", got)
+	}
+}
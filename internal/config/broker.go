@@ -0,0 +1,70 @@
+package config
+
+import (
+	"errors"
+	"log"
+	"sync"
+)
+
+// ErrTooManySubscribers is returned when Config.MaxSubscribers streams are already open.
+var ErrTooManySubscribers = errors.New("too many subscribers")
+
+// Subscription receives the events published after it was created.
+// The channel is closed when the subscriber falls too far behind.
+type Subscription struct {
+	C      chan Event
+	server string
+}
+
+// Broker fans events out to the open streams.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+}
+
+// Stream is the shared broker the event log publishes to.
+var Stream = &Broker{subscribers: make(map[*Subscription]struct{})}
+
+// subscriptionBuffer is how many events a subscriber may lag behind before it is dropped.
+const subscriptionBuffer = 64
+
+// Subscribe opens a subscription to the events of one server, or of every server if server is empty.
+func (b *Broker) Subscribe(server string) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.subscribers) >= Config.MaxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+	sub := &Subscription{C: make(chan Event, subscriptionBuffer), server: server}
+	b.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+// Unsubscribe closes the subscription.
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.C)
+	}
+}
+
+// Publish delivers the event to every matching subscriber without blocking. A subscriber
+// whose buffer is full is dropped, and can resume from the last event it received.
+func (b *Broker) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subscribers {
+		if sub.server != "" && sub.server != event.Server {
+			continue
+		}
+		select {
+		case sub.C <- event:
+		default:
+			log.Printf("Dropping slow stream subscriber for %q", sub.server)
+			delete(b.subscribers, sub)
+			close(sub.C)
+		}
+	}
+}
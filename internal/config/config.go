@@ -4,19 +4,48 @@ import (
 	"flag"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Configuration constants
 var Config = struct {
 	Port string
     ConfigJson string
+	ConfigJsonMode string
+	ConfigJsonDryRun bool
 	CliConfig string
 	LogsPath string
+	DataPath string
+	AdminToken string
+	AuthConfig string
+	ApiKeys string
+	RconWorkers int
+	RconDeadline time.Duration
+	PollInterval time.Duration
+	MaxSubscribers int
+	BreakerThreshold int
+	BreakerCooldown time.Duration
+	Lenient bool
 }{
 	Port:         "3000",
     ConfigJson:   "",
+	ConfigJsonMode: "merge",
+	ConfigJsonDryRun: false,
 	CliConfig:    "/config/rcon.yaml",
 	LogsPath:     "/logs",
+	DataPath:     "/data",
+	AdminToken:   "",
+	AuthConfig:   "/config/auth.yaml",
+	ApiKeys:      "",
+	RconWorkers:  4,
+	RconDeadline: 15 * time.Second,
+	PollInterval: 15 * time.Second,
+	MaxSubscribers: 100,
+	BreakerThreshold: 5,
+	BreakerCooldown: 30 * time.Second,
+	Lenient: false,
 }
 
 // setIfNotEmpty sets the value of a string variable if the corresponding environment variable is not empty.
@@ -26,35 +55,126 @@ func setIfNotEmpty(key string, value *string) {
 	}
 }
 
+// setIntIfNotEmpty sets the value of an int variable if the corresponding environment variable is a valid number.
+func setIntIfNotEmpty(key string, value *int) {
+	if env := os.Getenv(key); env != "" {
+		parsed, err := strconv.Atoi(env)
+		if err != nil {
+			log.Fatalf("Invalid value for %s: %v", key, err)
+		}
+		*value = parsed
+	}
+}
+
+// setBoolIfNotEmpty sets the value of a bool variable if the corresponding environment variable is a valid bool.
+func setBoolIfNotEmpty(key string, value *bool) {
+	if env := os.Getenv(key); env != "" {
+		parsed, err := strconv.ParseBool(env)
+		if err != nil {
+			log.Fatalf("Invalid value for %s: %v", key, err)
+		}
+		*value = parsed
+	}
+}
+
+// setDurationIfNotEmpty sets the value of a duration variable if the corresponding environment variable is a valid duration.
+func setDurationIfNotEmpty(key string, value *time.Duration) {
+	if env := os.Getenv(key); env != "" {
+		parsed, err := time.ParseDuration(env)
+		if err != nil {
+			log.Fatalf("Invalid value for %s: %v", key, err)
+		}
+		*value = parsed
+	}
+}
+
 // setConfigFromEnv sets configuration values from environment variables.
 func setConfigFromEnv() {
 	setIfNotEmpty("PORT", &Config.Port)
 	setIfNotEmpty("CLI_CONFIG", &Config.CliConfig)
 	setIfNotEmpty("CONFIG_JSON", &Config.ConfigJson)
+	setIfNotEmpty("CONFIG_JSON_MODE", &Config.ConfigJsonMode)
+	setBoolIfNotEmpty("CONFIG_JSON_DRY_RUN", &Config.ConfigJsonDryRun)
 	setIfNotEmpty("LOGS_PATH", &Config.LogsPath)
+	setIfNotEmpty("DATA_PATH", &Config.DataPath)
+	setIfNotEmpty("ADMIN_TOKEN", &Config.AdminToken)
+	setIfNotEmpty("AUTH_CONFIG", &Config.AuthConfig)
+	setIfNotEmpty("API_KEYS", &Config.ApiKeys)
+	setIntIfNotEmpty("RCON_WORKERS", &Config.RconWorkers)
+	setDurationIfNotEmpty("RCON_DEADLINE", &Config.RconDeadline)
+	setDurationIfNotEmpty("POLL_INTERVAL", &Config.PollInterval)
+	setIntIfNotEmpty("MAX_SUBSCRIBERS", &Config.MaxSubscribers)
+	setIntIfNotEmpty("BREAKER_THRESHOLD", &Config.BreakerThreshold)
+	setDurationIfNotEmpty("BREAKER_COOLDOWN", &Config.BreakerCooldown)
+	setBoolIfNotEmpty("LENIENT", &Config.Lenient)
+	setIfNotEmpty("API_BASE", &ApiConfig.Base)
+	setIfNotEmpty("API_SEARCH_PATH", &ApiConfig.Search)
+	setIfNotEmpty("API_LIST_PATH", &ApiConfig.List)
+	setDurationIfNotEmpty("API_TIMEOUT", &ApiConfig.Timeout)
+	setDurationIfNotEmpty("API_CACHE_TTL", &ApiConfig.CacheTTL)
+	setIntIfNotEmpty("API_MAX_PAGES", &ApiConfig.MaxPages)
 }
 
-// init parses flags and sets configuration.
-func init() {
+// Load sets the configuration from environment variables and flags. main calls it
+// before anything else; tests don't, so they run with the defaults.
+func Load() {
 	// Set configuration from environment variables
 	setConfigFromEnv()
 
 	flag.StringVar(&Config.Port, "port", Config.Port, "Server port")
 	flag.StringVar(&Config.CliConfig, "cli-config", Config.CliConfig, "path to rcon.yaml")
 	flag.StringVar(&Config.ConfigJson, "config-json", Config.ConfigJson, "json object")
+	flag.StringVar(&Config.ConfigJsonMode, "config-json-mode", Config.ConfigJsonMode, "merge the config-json servers into rcon.yaml, or replace it with them")
+	flag.BoolVar(&Config.ConfigJsonDryRun, "config-json-dry-run", Config.ConfigJsonDryRun, "print the changes config-json would make to rcon.yaml and exit")
 	flag.StringVar(&Config.LogsPath, "logs-path", Config.LogsPath, "Logs path")
+	flag.StringVar(&Config.DataPath, "data-path", Config.DataPath, "Data path for the player history database")
+	flag.StringVar(&Config.AdminToken, "admin-token", Config.AdminToken, "API key with the admin role")
+	flag.StringVar(&Config.AuthConfig, "auth-config", Config.AuthConfig, "path to auth.yaml with API keys")
+	flag.StringVar(&Config.ApiKeys, "api-keys", Config.ApiKeys, "API keys as key:role[:server1|server2], comma separated")
+	flag.IntVar(&Config.RconWorkers, "rcon-workers", Config.RconWorkers, "Max parallel server queries for /rcon/")
+	flag.DurationVar(&Config.RconDeadline, "rcon-deadline", Config.RconDeadline, "Overall deadline for /rcon/")
+	flag.DurationVar(&Config.PollInterval, "poll-interval", Config.PollInterval, "Background poll interval, 0 to disable")
+	flag.IntVar(&Config.MaxSubscribers, "max-subscribers", Config.MaxSubscribers, "Max concurrent live stream subscribers")
+	flag.IntVar(&Config.BreakerThreshold, "breaker-threshold", Config.BreakerThreshold, "Failures in a row before a server's circuit breaker opens, 0 to disable")
+	flag.DurationVar(&Config.BreakerCooldown, "breaker-cooldown", Config.BreakerCooldown, "How long an open circuit breaker waits before probing the server")
+	flag.BoolVar(&Config.Lenient, "lenient", Config.Lenient, "Start and reload even if rcon.yaml fails the strict checks")
+	flag.StringVar(&ApiConfig.Base, "api-base", ApiConfig.Base, "Base URL of the public server list API")
+	flag.StringVar(&ApiConfig.Search, "api-search-path", ApiConfig.Search, "Search path of the public server list API")
+	flag.StringVar(&ApiConfig.List, "api-list-path", ApiConfig.List, "List path of the public server list API")
+	flag.DurationVar(&ApiConfig.Timeout, "api-timeout", ApiConfig.Timeout, "Timeout of each server list API request")
+	flag.DurationVar(&ApiConfig.CacheTTL, "api-cache-ttl", ApiConfig.CacheTTL, "How long server list searches are cached, 0 to disable")
+	flag.IntVar(&ApiConfig.MaxPages, "api-max-pages", ApiConfig.MaxPages, "Max pages fetched per server list search, 0 for no limit")
 	flag.Parse()
-	// Check if CONFIG_JSON is set
-	if Config.ConfigJson != "" {
-		// Update the existing config file if it exists, otherwise create a new one
-		err := GenerateConfigFromJSON(Config.ConfigJson, Config.CliConfig, Config.LogsPath)
-		if err != nil {
-			log.Fatalf("Error generating config from JSON: %v", err)
-		}
-	}
+	ApiConfig.Base = strings.TrimSuffix(ApiConfig.Base, "/")
 
 	// Log the set flags
 	log.Printf("Server port: %s", Config.Port)
 	log.Printf("Root path to rcon.yaml: %s", Config.CliConfig)
 	log.Printf("Logs path: %s", Config.LogsPath)
+	log.Printf("Data path: %s", Config.DataPath)
+	log.Printf("RCON workers: %d, deadline: %s", Config.RconWorkers, Config.RconDeadline)
+	log.Printf("Path to auth.yaml: %s", Config.AuthConfig)
+	log.Printf("Server list API: %s (search %s, list %s)", ApiConfig.Base, ApiConfig.Search, ApiConfig.List)
+	log.Printf("Server list API timeout: %s, cache TTL: %s, max pages: %d", ApiConfig.Timeout, ApiConfig.CacheTTL, ApiConfig.MaxPages)
+}
+
+// ApplyConfigJSON writes the servers of CONFIG_JSON to rcon.yaml when it is set, or only
+// prints the changes and exits with -config-json-dry-run. main calls it after Load, but
+// not for subcommands such as validate-config that must leave rcon.yaml alone.
+func ApplyConfigJSON() {
+	if Config.ConfigJson == "" {
+		return
+	}
+	if Config.ConfigJsonMode != "merge" && Config.ConfigJsonMode != "replace" {
+		log.Fatalf("Invalid value for CONFIG_JSON_MODE: %q, use merge or replace", Config.ConfigJsonMode)
+	}
+	// Merge into the existing config file if it exists, otherwise create a new one
+	err := GenerateConfigFromJSON(Config.ConfigJson, Config.CliConfig, Config.LogsPath,
+		Config.ConfigJsonMode == "merge", Config.ConfigJsonDryRun)
+	if err != nil {
+		log.Fatalf("Error generating config from JSON: %v", err)
+	}
+	if Config.ConfigJsonDryRun {
+		os.Exit(0)
+	}
 }
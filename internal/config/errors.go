@@ -0,0 +1,97 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+
+	"github.com/gorcon/rcon"
+)
+
+// Codes of the errors talking to a game server
+const (
+	ErrCodeDial        = "dial_failed"    // the server could not be reached
+	ErrCodeAuth        = "auth_failed"    // the server refused the credentials
+	ErrCodeTimeout     = "timeout"        // the server did not answer in time
+	ErrCodeUpstream    = "upstream_error" // the server answered with something unexpected
+	ErrCodeConfig      = "config_error"   // the server's entry in rcon.yaml is unusable
+	ErrCodeCircuitOpen = "circuit_open"   // the server failed too often and is not asked for a while
+)
+
+// ServerError is a failure talking to a game server, classified by what went wrong.
+type ServerError struct {
+	Code   string
+	Server string
+	Err    error
+}
+
+func (e *ServerError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ServerError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether the same request may succeed later without changing the config.
+func (e *ServerError) Retryable() bool {
+	return IsRetryableCode(e.Code)
+}
+
+// IsRetryableCode reports whether errors with the given code are worth retrying.
+func IsRetryableCode(code string) bool {
+	switch code {
+	case ErrCodeDial, ErrCodeTimeout, ErrCodeUpstream, ErrCodeCircuitOpen:
+		return true
+	default:
+		return false
+	}
+}
+
+// AsServerError classifies err as a ServerError of the named server.
+func AsServerError(server string, err error) *ServerError {
+	var serverErr *ServerError
+	if errors.As(err, &serverErr) {
+		if serverErr.Server == "" {
+			serverErr.Server = server
+		}
+		return serverErr
+	}
+	return &ServerError{Code: errorCode(err), Server: server, Err: err}
+}
+
+// errorCode tells timeouts, refused credentials and unreachable servers apart.
+func errorCode(err error) string {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) ||
+		(errors.As(err, &netErr) && netErr.Timeout()) {
+		return ErrCodeTimeout
+	}
+	if errors.Is(err, rcon.ErrAuthFailed) {
+		return ErrCodeAuth
+	}
+	var statusErr *restStatusError
+	if errors.As(err, &statusErr) && (statusErr.Status == 401 || statusErr.Status == 403) {
+		return ErrCodeAuth
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return ErrCodeDial
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return ErrCodeDial
+	}
+	return ErrCodeUpstream
+}
+
+// OfflineInfo is the status reported for a server that could not be queried.
+func OfflineInfo(err error) *ServerInfo {
+	info := &ServerInfo{Players: Players{List: []Player{}}, Error: Redact(err.Error())}
+	var serverErr *ServerError
+	if errors.As(err, &serverErr) {
+		info.ErrorCode = serverErr.Code
+	}
+	return info
+}
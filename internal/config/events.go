@@ -0,0 +1,139 @@
+package config
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	EventPlayerJoined  = "player_joined"
+	EventPlayerLeft    = "player_left"
+	EventStatus        = "status"
+	EventServerOnline  = "server_online"
+	EventServerOffline = "server_offline"
+)
+
+// Event log constants
+var EventsConfig = struct {
+	BufferSize int
+}{
+	BufferSize: 200, // recent events kept per server
+}
+
+// Event is something that happened on a server between two snapshots.
+type Event struct {
+	ID     uint64      `json:"id,omitempty"`
+	Type   string      `json:"type"`
+	Server string      `json:"server"`
+	Time   time.Time   `json:"time"`
+	Player *Player     `json:"player,omitempty"`
+	Info   *ServerInfo `json:"info,omitempty"`
+}
+
+// EventLog keeps the most recent events of every server.
+// Event IDs increase across all servers, so clients can resume with the last ID they saw.
+type EventLog struct {
+	mu     sync.Mutex
+	lastID uint64
+	events map[string][]Event
+}
+
+// Events is the shared log the snapshot store records into.
+var Events = &EventLog{events: make(map[string][]Event)}
+
+// Record appends a new event for the server, publishes it and returns it.
+func (l *EventLog) Record(server string, eventType string, player *Player, at time.Time) Event {
+	l.mu.Lock()
+	l.lastID++
+	event := Event{ID: l.lastID, Type: eventType, Server: server, Time: at, Player: player}
+	events := append(l.events[server], event)
+	if len(events) > EventsConfig.BufferSize {
+		events = events[len(events)-EventsConfig.BufferSize:]
+	}
+	l.events[server] = events
+	l.mu.Unlock()
+
+	Stream.Publish(event)
+	Notifier.Enqueue(event)
+	return event
+}
+
+// Status publishes a changed server status. Status events are not kept, a
+// reconnecting client gets the current status instead of a replay.
+func (l *EventLog) Status(server string, info *ServerInfo, at time.Time) Event {
+	l.mu.Lock()
+	l.lastID++
+	event := Event{ID: l.lastID, Type: EventStatus, Server: server, Time: at, Info: info}
+	l.mu.Unlock()
+
+	Stream.Publish(event)
+	return event
+}
+
+// RecentAll returns the events of every server newer than since, oldest first.
+func (l *EventLog) RecentAll(since uint64) []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result := []Event{}
+	for _, events := range l.events {
+		for _, event := range events {
+			if event.ID > since {
+				result = append(result, event)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// Recent returns up to limit events of the server newer than since, oldest first.
+func (l *EventLog) Recent(server string, since uint64, limit int) []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result := []Event{}
+	for _, event := range l.events[server] {
+		if event.ID > since {
+			result = append(result, event)
+		}
+	}
+	if limit > 0 && len(result) > limit {
+		result = result[len(result)-limit:]
+	}
+	return result
+}
+
+// recordPlayerChanges compares two player lists of a server and records who joined and left.
+func recordPlayerChanges(server string, previous []Player, current []Player, at time.Time) {
+	before := make(map[string]bool, len(previous))
+	for _, player := range previous {
+		before[playerKey(player)] = true
+	}
+	after := make(map[string]bool, len(current))
+	for _, player := range current {
+		after[playerKey(player)] = true
+	}
+
+	for _, player := range previous {
+		if !after[playerKey(player)] {
+			player := player
+			Events.Record(server, EventPlayerLeft, &player, at)
+		}
+	}
+	for _, player := range current {
+		if !before[playerKey(player)] {
+			player := player
+			Events.Record(server, EventPlayerJoined, &player, at)
+		}
+	}
+}
+
+// playerKey identifies a player across snapshots. The Steam ID is stable, the
+// player UID is only used when no Steam ID is reported.
+func playerKey(player Player) string {
+	if player.SID != "" {
+		return player.SID
+	}
+	return player.PID
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// GetAllRconData queries every server in parallel with at most Config.RconWorkers
+// queries in flight. Servers that have not answered when ctx is done are reported
+// as offline instead of failing the whole result.
+func GetAllRconData(ctx context.Context, servers map[string]ConfigServer) map[string]*ServerInfo {
+	results := make(map[string]*ServerInfo, len(servers))
+	var mu sync.Mutex
+
+	workers := Config.RconWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(servers) {
+		workers = len(servers)
+	}
+
+	names := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				serverInfo := queryWithContext(ctx, servers[name])
+				mu.Lock()
+				results[name] = serverInfo
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// Hand out servers until every one is queued or the deadline passes
+feed:
+	for name := range servers {
+		select {
+		case names <- name:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(names)
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	// Copy out under the lock, since workers may still be finishing late queries
+	mu.Lock()
+	defer mu.Unlock()
+	serverDataMap := make(map[string]*ServerInfo, len(servers))
+	for name := range servers {
+		serverInfo, ok := results[name]
+		if !ok {
+			log.Printf("No answer from %s before the deadline", name)
+			serverInfo = OfflineInfo(AsServerError(name, ctx.Err()))
+		}
+		serverDataMap[name] = serverInfo
+	}
+	return serverDataMap
+}
+
+// queryWithContext runs GetRconData and gives up waiting for it once ctx is done.
+func queryWithContext(ctx context.Context, configServer ConfigServer) *ServerInfo {
+	result := make(chan *ServerInfo, 1)
+	go func() {
+		serverInfo, err := GetRconData(configServer)
+		if err != nil {
+			serverInfo = OfflineInfo(err)
+		}
+		result <- serverInfo
+	}()
+
+	select {
+	case serverInfo := <-result:
+		return serverInfo
+	case <-ctx.Done():
+		return OfflineInfo(AsServerError(configServer.Name, ctx.Err()))
+	}
+}
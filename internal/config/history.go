@@ -0,0 +1,308 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	playersBucket = []byte("players")
+	serversBucket = []byte("servers")
+)
+
+// PlayerRecord is the stored history of one player, either across all servers
+// or on a single server.
+type PlayerRecord struct {
+	SteamID     string    `json:"steamId"`
+	Name        string    `json:"name"`
+	PID         string    `json:"pid"`
+	Server      string    `json:"server,omitempty"`
+	FirstSeen   time.Time `json:"firstSeen"`
+	LastSeen    time.Time `json:"lastSeen"`
+	Playtime    int64     `json:"playtimeSeconds"`
+	Sessions    int       `json:"sessions"`
+	Online      bool      `json:"online"`
+	OnlineSince time.Time `json:"onlineSince"`
+}
+
+// PlayerHistory is a player's overall record plus one record per server played on.
+type PlayerHistory struct {
+	PlayerRecord
+	Servers []PlayerRecord `json:"servers"`
+}
+
+// HistoryStore persists player sightings in a bbolt database.
+type HistoryStore struct {
+	db *bolt.DB
+}
+
+// History is the shared player history store, nil when it could not be opened.
+var History *HistoryStore
+
+// OpenHistory opens (or creates) the player history database in dataPath.
+// Sessions still open from the last run are closed at their last sighting, so the
+// time the API was down doesn't count as playtime.
+func OpenHistory(dataPath string) (*HistoryStore, error) {
+	if err := os.MkdirAll(dataPath, 0755); err != nil {
+		return nil, fmt.Errorf("error creating data path: %v", err)
+	}
+	db, err := bolt.Open(filepath.Join(dataPath, "history.db"), 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("error opening history database: %v", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(playersBucket); err != nil {
+			return err
+		}
+		servers, err := tx.CreateBucketIfNotExists(serversBucket)
+		if err != nil {
+			return err
+		}
+		if err := closeOpenSessions(tx.Bucket(playersBucket)); err != nil {
+			return err
+		}
+		return servers.ForEachBucket(func(name []byte) error {
+			return closeOpenSessions(servers.Bucket(name))
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error preparing history database: %v", err)
+	}
+	return &HistoryStore{db: db}, nil
+}
+
+// Close closes the database.
+func (h *HistoryStore) Close() error {
+	return h.db.Close()
+}
+
+// Record updates the history with the players currently online on a server.
+// A player seen for the first time since being offline starts a new session, and a
+// player that is gone has the time until it was last seen added to its playtime.
+func (h *HistoryStore) Record(server string, players []Player, at time.Time) {
+	if h == nil {
+		return
+	}
+	err := h.db.Update(func(tx *bolt.Tx) error {
+		global := tx.Bucket(playersBucket)
+		perServer, err := tx.Bucket(serversBucket).CreateBucketIfNotExists([]byte(server))
+		if err != nil {
+			return err
+		}
+
+		present := make(map[string]bool, len(players))
+		for _, player := range players {
+			key := playerKey(player)
+			if key == "" {
+				continue
+			}
+			present[key] = true
+			if err := updateRecord(perServer, key, server, player, at); err != nil {
+				return err
+			}
+			if err := updateRecord(global, key, server, player, at); err != nil {
+				return err
+			}
+		}
+
+		// Close the sessions of everyone who is no longer on this server.
+		// Collect them first, bbolt buckets must not be changed while iterating.
+		var left []PlayerRecord
+		err = perServer.ForEach(func(k, v []byte) error {
+			if present[string(k)] {
+				return nil
+			}
+			var record PlayerRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+			if record.Online {
+				left = append(left, record)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, record := range left {
+			closeSession(&record)
+			if err := putRecord(perServer, record.SteamID, record); err != nil {
+				return err
+			}
+
+			var overall PlayerRecord
+			found, err := getRecord(global, record.SteamID, &overall)
+			if err != nil {
+				return err
+			}
+			if found && overall.Online && overall.Server == server {
+				closeSession(&overall)
+				if err := putRecord(global, record.SteamID, overall); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error recording player history for %s: %v", server, err)
+	}
+}
+
+// CloseSessions ends the running sessions on a server at each player's last sighting,
+// for polls that can't tell who is online. Otherwise an outage would count as playtime
+// for everyone still listed once the server answers again.
+func (h *HistoryStore) CloseSessions(server string) {
+	// Nobody is present, so Record closes every open session at its last sighting
+	h.Record(server, nil, time.Now())
+}
+
+// Player returns the history of one player, or nil if the player was never seen.
+func (h *HistoryStore) Player(steamID string) (*PlayerHistory, error) {
+	var history *PlayerHistory
+	err := h.db.View(func(tx *bolt.Tx) error {
+		var overall PlayerRecord
+		found, err := getRecord(tx.Bucket(playersBucket), steamID, &overall)
+		if err != nil || !found {
+			return err
+		}
+		history = &PlayerHistory{PlayerRecord: withCurrentPlaytime(overall), Servers: []PlayerRecord{}}
+		return tx.Bucket(serversBucket).ForEachBucket(func(name []byte) error {
+			var record PlayerRecord
+			found, err := getRecord(tx.Bucket(serversBucket).Bucket(name), steamID, &record)
+			if err != nil || !found {
+				return err
+			}
+			history.Servers = append(history.Servers, withCurrentPlaytime(record))
+			return nil
+		})
+	})
+	return history, err
+}
+
+// ServerPlayers returns one page of the players seen on a server, most recently
+// seen first, along with the total number of players.
+func (h *HistoryStore) ServerPlayers(server string, offset int, limit int) ([]PlayerRecord, int, error) {
+	records := []PlayerRecord{}
+	err := h.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(serversBucket).Bucket([]byte(server))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var record PlayerRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+			records = append(records, withCurrentPlaytime(record))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].LastSeen.Equal(records[j].LastSeen) {
+			return records[i].SteamID < records[j].SteamID
+		}
+		return records[i].LastSeen.After(records[j].LastSeen)
+	})
+	total := len(records)
+	if offset > total {
+		offset = total
+	}
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+	return records[offset:end], total, nil
+}
+
+// updateRecord marks a player as seen on server at the given time.
+func updateRecord(bucket *bolt.Bucket, key string, server string, player Player, at time.Time) error {
+	var record PlayerRecord
+	found, err := getRecord(bucket, key, &record)
+	if err != nil {
+		return err
+	}
+	if !found {
+		record = PlayerRecord{SteamID: key, FirstSeen: at}
+	}
+	if !record.Online {
+		record.Online = true
+		record.OnlineSince = at
+		record.Sessions++
+	}
+	record.Name = player.Name
+	record.PID = player.PID
+	record.Server = server
+	record.LastSeen = at
+	return putRecord(bucket, key, record)
+}
+
+// closeOpenSessions closes the session of every player in the bucket marked online.
+func closeOpenSessions(bucket *bolt.Bucket) error {
+	// Collect them first, bbolt buckets must not be changed while iterating
+	var open []PlayerRecord
+	err := bucket.ForEach(func(k, v []byte) error {
+		var record PlayerRecord
+		if err := json.Unmarshal(v, &record); err != nil {
+			return err
+		}
+		if record.Online {
+			open = append(open, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, record := range open {
+		closeSession(&record)
+		if err := putRecord(bucket, record.SteamID, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeSession ends the running session, counting it up to the last sighting.
+func closeSession(record *PlayerRecord) {
+	record.Playtime += int64(record.LastSeen.Sub(record.OnlineSince).Seconds())
+	record.Online = false
+	record.OnlineSince = time.Time{}
+}
+
+// withCurrentPlaytime includes the running session in the playtime of an online player.
+func withCurrentPlaytime(record PlayerRecord) PlayerRecord {
+	if record.Online {
+		record.Playtime += int64(record.LastSeen.Sub(record.OnlineSince).Seconds())
+	}
+	return record
+}
+
+func getRecord(bucket *bolt.Bucket, key string, record *PlayerRecord) (bool, error) {
+	data := bucket.Get([]byte(key))
+	if data == nil {
+		return false, nil
+	}
+	return true, json.Unmarshal(data, record)
+}
+
+func putRecord(bucket *bolt.Bucket, key string, record PlayerRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(key), data)
+}
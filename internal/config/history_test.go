@@ -0,0 +1,97 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistoryRestartClosesSessions(t *testing.T) {
+	dataPath := t.TempDir()
+	history, err := OpenHistory(dataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	players := []Player{{Name: "Alice", PID: "1111", SID: "76561198000000001"}}
+	history.Record("alpha", players, start)
+	history.Record("alpha", players, start.Add(10*time.Minute))
+	if err := history.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The API is down for an hour while the player keeps playing
+	history, err = OpenHistory(dataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+	history.Record("alpha", players, start.Add(70*time.Minute))
+	history.Record("alpha", players, start.Add(75*time.Minute))
+
+	record, err := history.Player("76561198000000001")
+	if err != nil || record == nil {
+		t.Fatalf("Player = %v, %v", record, err)
+	}
+	for _, got := range append([]PlayerRecord{record.PlayerRecord}, record.Servers...) {
+		if got.Playtime != 15*60 || got.Sessions != 2 || !got.Online {
+			t.Errorf("%q record: playtime %d, sessions %d, online %v, want 900, 2, online",
+				got.Server, got.Playtime, got.Sessions, got.Online)
+		}
+		if !got.OnlineSince.Equal(start.Add(70 * time.Minute)) {
+			t.Errorf("%q record online since %v, want the first poll after the restart", got.Server, got.OnlineSince)
+		}
+	}
+}
+
+func TestHistoryOutageClosesSessions(t *testing.T) {
+	history, err := OpenHistory(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+	start := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	players := []Player{{Name: "Alice", PID: "1111", SID: "76561198000000001"}}
+	history.Record("alpha", players, start)
+	history.Record("alpha", players, start.Add(10*time.Minute))
+
+	// The server is unreachable for an hour, the player is still listed afterwards
+	history.CloseSessions("alpha")
+	history.Record("alpha", players, start.Add(70*time.Minute))
+	history.Record("alpha", players, start.Add(75*time.Minute))
+
+	record, err := history.Player("76561198000000001")
+	if err != nil || record == nil {
+		t.Fatalf("Player = %v, %v", record, err)
+	}
+	for _, got := range append([]PlayerRecord{record.PlayerRecord}, record.Servers...) {
+		if got.Playtime != 15*60 || got.Sessions != 2 {
+			t.Errorf("%q record: playtime %d, sessions %d, want 900, 2", got.Server, got.Playtime, got.Sessions)
+		}
+	}
+}
+
+func TestSnapshotStoreClosesSessionsWhenOffline(t *testing.T) {
+	history, err := OpenHistory(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := History
+	History = history
+	t.Cleanup(func() {
+		History = saved
+		history.Close()
+	})
+
+	alice := Player{Name: "Alice", PID: "1111", SID: "76561198000000001"}
+	store := &SnapshotStore{snapshots: make(map[string]Snapshot)}
+	store.Set("sessions-test", &ServerInfo{Online: true, Name: "Alpha", Version: "v0.3.1", Players: Players{Count: 1, List: []Player{alice}}})
+	store.Set("sessions-test", &ServerInfo{Error: "rcon: unreachable", ErrorCode: ErrCodeDial, Players: Players{List: []Player{}}})
+
+	record, err := history.Player(alice.SID)
+	if err != nil || record == nil {
+		t.Fatalf("Player = %v, %v", record, err)
+	}
+	if record.Online || record.Sessions != 1 {
+		t.Errorf("after going offline: online %v with %d sessions, want a closed session", record.Online, record.Sessions)
+	}
+}
@@ -1,47 +1,340 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
 )
 
 type JsonServerConfig struct {
 	Name     string `json:"name"`
 	Address  string `json:"address"`
+	Username string `json:"username"`
 	Password string `json:"password"`
+	PasswordFile string `json:"password_file"`
+	PasswordEnv  string `json:"password_env"`
 	Type     string `json:"type"`
 	Timeout  string `json:"timeout"`
+	ReadTimeout  string `json:"read_timeout"`
+	Retries      int    `json:"retries"`
+	RetryBackoff string `json:"retry_backoff"`
 }
 
 type JsonConfigData struct {
 	Servers []JsonServerConfig `json:"servers"`
 }
 
-func GenerateConfigFromJSON(configJSON string, outputPath string, logs string) error {
+// GenerateConfigFromJSON writes the servers of configJSON to the rcon.yaml at outputPath.
+// With merge, they are set into the existing file: other servers, other keys and comments
+// stay as they are. Otherwise the file holds only the servers of configJSON.
+// With dryRun, the file is left alone and the diff of the change is printed instead.
+func GenerateConfigFromJSON(configJSON string, outputPath string, logs string, merge bool, dryRun bool) error {
 	var JsonConfigData JsonConfigData
 	err := json.Unmarshal([]byte(configJSON), &JsonConfigData)
 	if err != nil {
 		return fmt.Errorf("error parsing JSON: %v", err)
 	}
 
-	// Prepare YAML content
-	yamlContent := ""
+	existing, err := os.ReadFile(outputPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error reading %s: %v", outputPath, err)
+	}
+
+	// Start from the existing file, or from an empty one
+	document := &yamlv3.Node{Kind: yamlv3.MappingNode}
+	root := &yamlv3.Node{Kind: yamlv3.DocumentNode, Content: []*yamlv3.Node{document}}
+	if merge && len(bytes.TrimSpace(existing)) > 0 {
+		var parsed yamlv3.Node
+		if err := yamlv3.Unmarshal(existing, &parsed); err != nil {
+			return fmt.Errorf("error parsing %s to merge into it: %v", outputPath, err)
+		}
+		if len(parsed.Content) == 0 || parsed.Content[0].Kind != yamlv3.MappingNode {
+			return fmt.Errorf("error merging into %s: it does not map server names to their settings", outputPath)
+		}
+		root, document = &parsed, parsed.Content[0]
+	}
+
+	seen := make(map[string]bool)
 	for _, server := range JsonConfigData.Servers {
-		yamlContent += fmt.Sprintf("%s:\n", server.Name)
-		yamlContent += fmt.Sprintf("  address: \"%s\"\n", server.Address)
-		yamlContent += fmt.Sprintf("  password: \"%s\"\n", server.Password)
-		yamlContent += fmt.Sprintf("  log: \"%s/%s.log\"\n", logs, server.Name)
-		yamlContent += fmt.Sprintf("  type: \"%s\"\n", server.Type)
-		yamlContent += fmt.Sprintf("  timeout: \"%s\"\n", server.Timeout)
+		if server.Name == "" {
+			return fmt.Errorf("error in JSON: every server needs a name")
+		}
+		if seen[server.Name] {
+			return fmt.Errorf("error in JSON: server '%s' is listed twice", server.Name)
+		}
+		seen[server.Name] = true
+		if countNonEmpty(server.Password, server.PasswordFile, server.PasswordEnv) > 1 {
+			return fmt.Errorf("error in JSON: server '%s' sets more than one of password, password_file and password_env", server.Name)
+		}
+		setServer(document, server, logs)
+	}
+
+	// Marshal YAML content, quoting every value that needs it
+	var yamlContent bytes.Buffer
+	encoder := yamlv3.NewEncoder(&yamlContent)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return fmt.Errorf("error writing YAML: %v", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("error writing YAML: %v", err)
+	}
+
+	if dryRun {
+		fmt.Print(unifiedDiff(outputPath, string(existing), yamlContent.String()))
+		return nil
 	}
 
 	// Write YAML content to file
-	err = ioutil.WriteFile(outputPath, []byte(yamlContent), 0644)
-	if err != nil {
+	if err := writeFileAtomic(outputPath, yamlContent.Bytes(), 0600); err != nil {
 		return fmt.Errorf("error writing YAML to file: %v", err)
 	}
 
 	fmt.Println("Config file generated successfully")
 	return nil
-}
\ No newline at end of file
+}
+
+// setServer sets the settings given in JSON on the server's entry, adding it if needed.
+// Settings the JSON leaves empty keep their value in the file.
+func setServer(document *yamlv3.Node, server JsonServerConfig, logs string) {
+	settings := mappingValue(document, server.Name)
+	if settings == nil {
+		settings = &yamlv3.Node{Kind: yamlv3.MappingNode}
+		document.Content = append(document.Content, stringNode(server.Name), settings)
+	} else if settings.Kind != yamlv3.MappingNode {
+		*settings = yamlv3.Node{Kind: yamlv3.MappingNode}
+	}
+
+	setString := func(key string, value string) {
+		if value != "" {
+			setMappingValue(settings, key, stringNode(value))
+		}
+	}
+	setString("address", server.Address)
+	setString("username", server.Username)
+	// Only one source of the password may be set, so a new one replaces the others
+	passwords := map[string]string{"password": server.Password, "password_file": server.PasswordFile, "password_env": server.PasswordEnv}
+	for key, value := range passwords {
+		if value != "" {
+			for other := range passwords {
+				if other != key {
+					deleteMappingValue(settings, other)
+				}
+			}
+			setString(key, value)
+		}
+	}
+	setString("log", fmt.Sprintf("%s/%s.log", logs, server.Name))
+	setString("type", server.Type)
+	setString("timeout", server.Timeout)
+	setString("read_timeout", server.ReadTimeout)
+	if server.Retries != 0 {
+		setMappingValue(settings, "retries", &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!int", Value: strconv.Itoa(server.Retries)})
+	}
+	setString("retry_backoff", server.RetryBackoff)
+}
+
+// stringNode is a YAML string, quoted on output whenever the value needs it.
+func stringNode(value string) *yamlv3.Node {
+	return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// setMappingValue replaces the value of key in the mapping, keeping its comments and
+// quoting style, or appends it.
+func setMappingValue(mapping *yamlv3.Node, key string, value *yamlv3.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			old := mapping.Content[i+1]
+			value.HeadComment, value.LineComment, value.FootComment = old.HeadComment, old.LineComment, old.FootComment
+			if old.Kind == yamlv3.ScalarNode && value.Kind == yamlv3.ScalarNode {
+				value.Style = old.Style
+			}
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, stringNode(key), value)
+}
+
+// deleteMappingValue removes key and its value from the mapping.
+func deleteMappingValue(mapping *yamlv3.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// writeFileAtomic writes to a temporary file next to path and renames it over path,
+// so readers such as the config watcher never see a half written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := file.Chmod(perm); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// passwordLine matches the key of a password setting, which diffs show redacted.
+var passwordLine = regexp.MustCompile(`^(\s*"?password"?\s*:)(\s.*)?$`)
+
+// redactPasswords replaces the values of password settings with Redacted, line for line.
+// Lines indented deeper than the key belong to the value, as in block scalars (password: |-)
+// and long values wrapped onto the next lines.
+func redactPasswords(lines []string) []string {
+	redacted := make([]string, len(lines))
+	keyIndent := -1
+	for i, line := range lines {
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if keyIndent >= 0 && (strings.TrimSpace(line) == "" || indent > keyIndent) {
+			if strings.TrimSpace(line) != "" {
+				line = line[:indent] + Redacted
+			}
+			redacted[i] = line
+			continue
+		}
+		keyIndent = -1
+		if match := passwordLine.FindStringSubmatch(line); match != nil {
+			line = match[1] + " " + Redacted
+			keyIndent = indent
+		}
+		redacted[i] = line
+	}
+	return redacted
+}
+
+// unifiedDiff shows the changes from before to after as a unified diff with 3 lines of context.
+func unifiedDiff(path string, before string, after string) string {
+	if before == after {
+		return fmt.Sprintf("%s: no changes\n", path)
+	}
+	a, b := splitLines(before), splitLines(after)
+	// Lines are compared as they are, so a changed password still shows, but printed redacted
+	shownA, shownB := redactPasswords(a), redactPasswords(b)
+
+	// Longest common subsequence table, small enough for config files
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// Walk the table into a list of kept, removed and added lines
+	type line struct {
+		op   byte
+		text string
+		a, b int // line numbers in before and after, counted from 0
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, line{' ', a[i], i, j})
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, line{'-', a[i], i, j})
+			i++
+		default:
+			lines = append(lines, line{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s (generated)\n", path, path)
+	const context = 3
+	for start := 0; start < len(lines); {
+		// Find the next change and the end of its hunk
+		first := start
+		for first < len(lines) && lines[first].op == ' ' {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+		from := max(first-context, 0)
+		to, kept := first, 0
+		for to < len(lines) && kept <= 2*context {
+			if lines[to].op == ' ' {
+				kept++
+			} else {
+				kept = 0
+			}
+			to++
+		}
+		to = min(to-kept+min(kept, context), len(lines))
+
+		countA, countB := 0, 0
+		for _, l := range lines[from:to] {
+			if l.op != '+' {
+				countA++
+			}
+			if l.op != '-' {
+				countB++
+			}
+		}
+		// An empty side is numbered after the line it follows, as diff does
+		startA, startB := lines[from].a+1, lines[from].b+1
+		if countA == 0 {
+			startA--
+		}
+		if countB == 0 {
+			startB--
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", startA, countA, startB, countB)
+		for _, l := range lines[from:to] {
+			var shown string
+			if l.op == '+' {
+				shown = shownB[l.b]
+			} else {
+				shown = shownA[l.a]
+			}
+			fmt.Fprintf(&out, "%c%s\n", l.op, shown)
+		}
+		start = to
+	}
+	return out.String()
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
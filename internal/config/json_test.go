@@ -0,0 +1,91 @@
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// captureStdout returns what run prints to standard output.
+func captureStdout(t *testing.T, run func()) string {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = saved }()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		output <- string(data)
+	}()
+	run()
+	writer.Close()
+	return <-output
+}
+
+func TestGenerateConfigFromJSONDryRunRedactsPasswords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rcon.yaml")
+	existing := "old:\n  address: 127.0.0.1:25575\n  password: |-\n    first-old-line\n    second-old-line\n  type: rcon\n"
+	if err := os.WriteFile(path, []byte(existing), 0600); err != nil {
+		t.Fatal(err)
+	}
+	configJSON := `{"servers": [
+		{"name": "old", "password": "changed-password"},
+		{"name": "new", "address": "127.0.0.1:25576", "password": "a\"b\\c\nd: e\nlast-line"}
+	]}`
+
+	var err error
+	diff := captureStdout(t, func() {
+		err = GenerateConfigFromJSON(configJSON, path, "/logs", true, true)
+	})
+	if err != nil {
+		t.Fatalf("GenerateConfigFromJSON: %v", err)
+	}
+	for _, secret := range []string{"old-line", "changed-password", `a"b`, "d: e", "last-line"} {
+		if strings.Contains(diff, secret) {
+			t.Errorf("diff shows %q:\n%s", secret, diff)
+		}
+	}
+	// The changed password is still shown as a change, and the new one is added
+	if !strings.Contains(diff, "-    [REDACTED]") || !strings.Contains(diff, "+  password: [REDACTED]") {
+		t.Errorf("diff does not show the password change:\n%s", diff)
+	}
+	if !strings.Contains(diff, "+  address: 127.0.0.1:25576") || !strings.Contains(diff, "   type: rcon") {
+		t.Errorf("diff lost other settings:\n%s", diff)
+	}
+	if data, _ := os.ReadFile(path); string(data) != existing {
+		t.Errorf("dry run changed the file to:\n%s", data)
+	}
+}
+
+func TestGenerateConfigFromJSONKeepsPasswords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rcon.yaml")
+	password := "a\"b\\c\nd: e\n  ${NOT_EXPANDED}"
+	configJSON := `{"servers": [{"name": "alpha", "address": "127.0.0.1:25575", "password": "a\"b\\c\nd: e\n  $${NOT_EXPANDED}"}]}`
+	captureStdout(t, func() {
+		if err := GenerateConfigFromJSON(configJSON, path, "/logs", true, false); err != nil {
+			t.Fatalf("GenerateConfigFromJSON: %v", err)
+		}
+	})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers, err := parseConfig(data)
+	if err != nil {
+		t.Fatalf("parsing the generated file: %v\n%s", err, data)
+	}
+	if got := servers["alpha"].Password; got != password {
+		t.Errorf("password %q, want %q", got, password)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("file mode %v, want 0600", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Registry holds every metric exported on /metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	commandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "palworld_rcon_command_duration_seconds",
+		Help:    "Latency of commands sent to Palworld servers.",
+		Buckets: []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"server", "backend", "command"})
+
+	commandErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "palworld_rcon_command_errors_total",
+		Help: "Commands sent to Palworld servers that failed.",
+	}, []string{"server", "backend", "command"})
+)
+
+var (
+	upDesc = prometheus.NewDesc("palworld_up",
+		"Whether the server answered the last poll.", []string{"server"}, nil)
+	playersDesc = prometheus.NewDesc("palworld_players_online",
+		"Players online at the last poll.", []string{"server"}, nil)
+	infoDesc = prometheus.NewDesc("palworld_info",
+		"Server name and version, always 1.", []string{"server", "name", "version"}, nil)
+	lastUpdateDesc = prometheus.NewDesc("palworld_last_update_timestamp_seconds",
+		"Unix time of the last poll.", []string{"server"}, nil)
+	fpsDesc = prometheus.NewDesc("palworld_server_fps",
+		"Server frames per second, REST backend only.", []string{"server"}, nil)
+	uptimeDesc = prometheus.NewDesc("palworld_server_uptime_seconds",
+		"Server uptime, REST backend only.", []string{"server"}, nil)
+)
+
+var (
+	breakerStateDesc = prometheus.NewDesc("palworld_circuit_breaker_state",
+		"Circuit breaker state of the server, 1 for the current state.", []string{"server", "state"}, nil)
+	breakerFailuresDesc = prometheus.NewDesc("palworld_circuit_breaker_failures",
+		"Failures in a row counted by the server's circuit breaker.", []string{"server"}, nil)
+)
+
+// breakerCollector exports the circuit breaker of every queried server at scrape time.
+type breakerCollector struct{}
+
+func (breakerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- breakerStateDesc
+	ch <- breakerFailuresDesc
+}
+
+func (breakerCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, breaker := range Breakers.All() {
+		for _, state := range BreakerStates {
+			value := 0.0
+			if breaker.State == state {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(breakerStateDesc, prometheus.GaugeValue, value, name, state)
+		}
+		ch <- prometheus.MustNewConstMetric(breakerFailuresDesc, prometheus.GaugeValue, float64(breaker.Failures), name)
+	}
+}
+
+// snapshotCollector exports the cached server snapshots at scrape time.
+type snapshotCollector struct{}
+
+func (snapshotCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- upDesc
+	ch <- playersDesc
+	ch <- infoDesc
+	ch <- lastUpdateDesc
+	ch <- fpsDesc
+	ch <- uptimeDesc
+}
+
+func (snapshotCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, snapshot := range Snapshots.All() {
+		info := snapshot.Info
+		up := 0.0
+		if info.Online {
+			up = 1
+		}
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, up, name)
+		ch <- prometheus.MustNewConstMetric(playersDesc, prometheus.GaugeValue, float64(info.Players.Count), name)
+		ch <- prometheus.MustNewConstMetric(lastUpdateDesc, prometheus.GaugeValue, float64(snapshot.UpdatedAt.Unix()), name)
+		if info.Online {
+			ch <- prometheus.MustNewConstMetric(infoDesc, prometheus.GaugeValue, 1, name, info.Name, info.Version)
+		}
+		if info.Metrics != nil {
+			ch <- prometheus.MustNewConstMetric(fpsDesc, prometheus.GaugeValue, float64(info.Metrics.FPS), name)
+			ch <- prometheus.MustNewConstMetric(uptimeDesc, prometheus.GaugeValue, float64(info.Metrics.Uptime), name)
+		}
+	}
+}
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		commandDuration,
+		commandErrors,
+		snapshotCollector{},
+		breakerCollector{},
+	)
+}
+
+// observeCommand records the latency and outcome of one command. Only the first
+// word of the command is used as label, arguments would explode the label set.
+func observeCommand(server string, backend string, command string, start time.Time, err error) {
+	if fields := strings.Fields(command); len(fields) > 0 {
+		command = strings.ToLower(fields[0])
+	}
+	commandDuration.WithLabelValues(server, backend, command).Observe(time.Since(start).Seconds())
+	if err != nil {
+		commandErrors.WithLabelValues(server, backend, command).Inc()
+	}
+}
@@ -0,0 +1,278 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Webhook formats
+const (
+	WebhookGeneric = "generic"
+	WebhookDiscord = "discord"
+	WebhookSlack   = "slack"
+)
+
+// WebhookConfig is one notification target of a server in rcon.yaml.
+type WebhookConfig struct {
+	URL         string   `yaml:"url" json:"url"`
+	Format      string   `yaml:"format" json:"format"`             // generic (default), discord or slack
+	Events      []string `yaml:"events" json:"events"`             // event types to send, all by default
+	Template    string   `yaml:"template" json:"template"`         // Go template for the body, overrides format
+	ContentType string   `yaml:"content_type" json:"content_type"` // application/json by default
+	Retries     *int     `yaml:"retries" json:"retries"`           // NotifierConfig.Retries by default
+}
+
+// Notifier constants
+var NotifierConfig = struct {
+	Workers     int
+	QueueSize   int
+	Timeout     time.Duration
+	Retries     int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	DeadLetter  string
+}{
+	Workers:     2,
+	QueueSize:   256,
+	Timeout:     10 * time.Second,
+	Retries:     3,
+	BackoffBase: 1 * time.Second,
+	BackoffMax:  30 * time.Second,
+	DeadLetter:  "webhooks-dead-letter.log",
+}
+
+// Default bodies for chat services, which only render a message field
+var webhookTemplates = map[string]string{
+	WebhookDiscord: `{"content": {{json .Message}}}`,
+	WebhookSlack:   `{"text": {{json .Message}}}`,
+}
+
+// WebhookPayload is the data webhook templates are executed with.
+type WebhookPayload struct {
+	Event
+	Message string `json:"message"`
+}
+
+// WebhookNotifier delivers server and player events to the webhooks configured for each server.
+type WebhookNotifier struct {
+	queue  chan Event
+	client *http.Client
+	once   sync.Once
+	mu     sync.Mutex // serializes dead-letter writes
+}
+
+// Notifier is the shared webhook notifier the event log feeds.
+var Notifier = &WebhookNotifier{
+	queue:  make(chan Event, NotifierConfig.QueueSize),
+	client: &http.Client{Timeout: NotifierConfig.Timeout},
+}
+
+// Start runs the delivery workers. Events enqueued before Start wait in the queue.
+func (n *WebhookNotifier) Start() {
+	n.once.Do(func() {
+		for i := 0; i < NotifierConfig.Workers; i++ {
+			go func() {
+				for event := range n.queue {
+					n.deliver(event)
+				}
+			}()
+		}
+	})
+}
+
+// Enqueue hands an event to the workers without blocking the caller.
+func (n *WebhookNotifier) Enqueue(event Event) {
+	select {
+	case n.queue <- event:
+	default:
+		log.Printf("Webhook queue is full, dropping %s event of %s", event.Type, event.Server)
+	}
+}
+
+// deliver sends the event to every webhook of its server that wants it.
+func (n *WebhookNotifier) deliver(event Event) {
+	server, err := GetServerConfig(event.Server)
+	if err != nil {
+		return
+	}
+	for i, webhook := range server.Webhooks {
+		if !webhook.wants(event.Type) {
+			continue
+		}
+		body, err := webhook.render(event)
+		if err != nil {
+			log.Printf("Error rendering webhook %d of %s: %v", i, event.Server, err)
+			n.deadLetter(event, i, webhook, nil, 0, err)
+			continue
+		}
+		attempts, err := n.post(webhook, body)
+		if err != nil {
+			log.Printf("Giving up on webhook %d of %s after %d attempts: %v", i, event.Server, attempts, err)
+			n.deadLetter(event, i, webhook, body, attempts, err)
+		}
+	}
+}
+
+// post sends the body, retrying network errors, 429 and 5xx answers with exponential backoff.
+func (n *WebhookNotifier) post(webhook WebhookConfig, body []byte) (int, error) {
+	retries := NotifierConfig.Retries
+	if webhook.Retries != nil {
+		retries = *webhook.Retries
+	}
+	contentType := webhook.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	backoff := NotifierConfig.BackoffBase
+	var err error
+	for attempt := 1; ; attempt++ {
+		var retryable bool
+		retryable, err = n.send(webhook.URL, contentType, body)
+		if err == nil {
+			return attempt, nil
+		}
+		if !retryable || attempt > retries {
+			return attempt, err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > NotifierConfig.BackoffMax {
+			backoff = NotifierConfig.BackoffMax
+		}
+	}
+}
+
+// send posts once and reports whether a failure is worth retrying.
+func (n *WebhookNotifier) send(target string, contentType string, body []byte) (bool, error) {
+	response, err := n.client.Post(target, contentType, bytes.NewReader(body))
+	if err != nil {
+		// Drop the URL from the error, it may contain the webhook token
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return true, err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 4096))
+
+	if response.StatusCode >= 200 && response.StatusCode <= 299 {
+		return false, nil
+	}
+	retryable := response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500
+	return retryable, fmt.Errorf("webhook answered %s", response.Status)
+}
+
+// deadLetter appends an undeliverable notification to the dead-letter log in Config.LogsPath.
+func (n *WebhookNotifier) deadLetter(event Event, index int, webhook WebhookConfig, body []byte, attempts int, cause error) {
+	entry := map[string]interface{}{
+		"time":     time.Now(),
+		"server":   event.Server,
+		"webhook":  index,
+		"host":     webhookHost(webhook.URL),
+		"event":    event,
+		"body":     string(body),
+		"attempts": attempts,
+		"error":    cause.Error(),
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Println("Error encoding dead letter:", err)
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	file, err := os.OpenFile(filepath.Join(Config.LogsPath, NotifierConfig.DeadLetter), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Println("Error opening dead-letter log:", err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		log.Println("Error writing dead-letter log:", err)
+	}
+}
+
+// wants reports whether the webhook subscribed to the event type.
+func (w WebhookConfig) wants(eventType string) bool {
+	if eventType == EventStatus {
+		return false
+	}
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, wanted := range w.Events {
+		if wanted == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// render builds the request body from the webhook's template or format.
+// Generic webhooks without a template get the event as JSON.
+func (w WebhookConfig) render(event Event) ([]byte, error) {
+	payload := WebhookPayload{Event: event, Message: eventMessage(event)}
+	text := w.Template
+	if text == "" {
+		text = webhookTemplates[strings.ToLower(w.Format)]
+	}
+	if text == "" {
+		return json.Marshal(payload)
+	}
+
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{"json": templateJSON}).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, payload); err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
+
+// eventMessage describes the event in one human readable line.
+func eventMessage(event Event) string {
+	switch event.Type {
+	case EventPlayerJoined:
+		return fmt.Sprintf("%s joined %s", event.Player.Name, event.Server)
+	case EventPlayerLeft:
+		return fmt.Sprintf("%s left %s", event.Player.Name, event.Server)
+	case EventServerOnline:
+		return fmt.Sprintf("%s is online", event.Server)
+	case EventServerOffline:
+		return fmt.Sprintf("%s is offline", event.Server)
+	default:
+		return fmt.Sprintf("%s: %s", event.Server, event.Type)
+	}
+}
+
+// templateJSON quotes a value for use inside a JSON template.
+func templateJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// webhookHost keeps webhook tokens, which often live in the URL path, out of the logs.
+func webhookHost(target string) string {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}
@@ -0,0 +1,234 @@
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookTarget is a local stand-in for a webhook receiver.
+type webhookTarget struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int // answered in turn, the last one repeated
+	bodies   []string
+	types    []string
+}
+
+func newWebhookTarget(t *testing.T, statuses ...int) *webhookTarget {
+	t.Helper()
+	target := &webhookTarget{statuses: statuses}
+	target.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		target.mu.Lock()
+		target.bodies = append(target.bodies, string(body))
+		target.types = append(target.types, r.Header.Get("Content-Type"))
+		status := http.StatusNoContent
+		if len(target.statuses) > 0 {
+			status = target.statuses[0]
+			if len(target.statuses) > 1 {
+				target.statuses = target.statuses[1:]
+			}
+		}
+		target.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(target.Close)
+	return target
+}
+
+func (w *webhookTarget) received() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.bodies...)
+}
+
+// newTestNotifier is a notifier with fast retries that writes dead letters to a temporary directory.
+func newTestNotifier(t *testing.T) *WebhookNotifier {
+	t.Helper()
+	savedNotifier, savedLogs := NotifierConfig, Config.LogsPath
+	NotifierConfig.BackoffBase = time.Millisecond
+	NotifierConfig.BackoffMax = 4 * time.Millisecond
+	Config.LogsPath = t.TempDir()
+	t.Cleanup(func() {
+		NotifierConfig, Config.LogsPath = savedNotifier, savedLogs
+	})
+	return &WebhookNotifier{queue: make(chan Event, 8), client: &http.Client{Timeout: time.Second}}
+}
+
+// useServers makes servers the loaded config for the rest of the test.
+func useServers(t *testing.T, servers map[string]ConfigServer) {
+	t.Helper()
+	saved := ServerConfigs.state.Load()
+	for name, server := range servers {
+		server.Name = name
+		servers[name] = server
+	}
+	ServerConfigs.state.Store(&configState{servers: servers})
+	t.Cleanup(func() { ServerConfigs.state.Store(saved) })
+}
+
+// readDeadLetters returns the entries of the dead-letter log.
+func readDeadLetters(t *testing.T) []map[string]interface{} {
+	t.Helper()
+	file, err := os.Open(filepath.Join(Config.LogsPath, NotifierConfig.DeadLetter))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var entries []map[string]interface{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("dead letter %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+var joinEvent = Event{
+	ID:     7,
+	Type:   EventPlayerJoined,
+	Server: "alpha",
+	Time:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	Player: &Player{Name: `Alice "A"`, PID: "1111", SID: "76561198000000001"},
+}
+
+func TestWebhookBodies(t *testing.T) {
+	tests := []struct {
+		name    string
+		webhook WebhookConfig
+		body    string
+		ctype   string
+	}{
+		{"discord", WebhookConfig{Format: "discord"}, `{"content": "Alice \"A\" joined alpha"}`, "application/json"},
+		{"slack", WebhookConfig{Format: "Slack"}, `{"text": "Alice \"A\" joined alpha"}`, "application/json"},
+		{
+			"template",
+			WebhookConfig{Format: "discord", Template: `{{.Type}} {{.Player.SID}} {{json .Message}}`, ContentType: "text/plain"},
+			`player_joined 76561198000000001 "Alice \"A\" joined alpha"`,
+			"text/plain",
+		},
+		{
+			"generic",
+			WebhookConfig{},
+			`{"id":7,"type":"player_joined","server":"alpha","time":"2024-01-02T03:04:05Z","player":{"name":"Alice \"A\"","pid":"1111","sid":"76561198000000001"},"message":"Alice \"A\" joined alpha"}`,
+			"application/json",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			notifier := newTestNotifier(t)
+			target := newWebhookTarget(t)
+			test.webhook.URL = target.URL
+			useServers(t, map[string]ConfigServer{"alpha": {Webhooks: []WebhookConfig{test.webhook}}})
+
+			notifier.deliver(joinEvent)
+			bodies := target.received()
+			if len(bodies) != 1 || bodies[0] != test.body {
+				t.Fatalf("received %q, want %q", bodies, test.body)
+			}
+			if target.types[0] != test.ctype {
+				t.Errorf("content type %q, want %q", target.types[0], test.ctype)
+			}
+		})
+	}
+}
+
+func TestWebhookEvents(t *testing.T) {
+	notifier := newTestNotifier(t)
+	target := newWebhookTarget(t)
+	useServers(t, map[string]ConfigServer{"alpha": {Webhooks: []WebhookConfig{
+		{URL: target.URL, Format: "slack", Events: []string{EventServerOffline}},
+	}}})
+
+	notifier.deliver(joinEvent)
+	notifier.deliver(Event{Type: EventStatus, Server: "alpha"})
+	notifier.deliver(Event{Type: EventServerOffline, Server: "alpha"})
+	if bodies := target.received(); len(bodies) != 1 || bodies[0] != `{"text": "alpha is offline"}` {
+		t.Errorf("received %q, want only the offline event", bodies)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	two := 2
+	tests := []struct {
+		name     string
+		statuses []int
+		retries  *int
+		requests int
+		failed   bool // ends in the dead-letter log
+	}{
+		{"ok", []int{http.StatusOK}, nil, 1, false},
+		{"5xx and 429 are retried", []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK}, nil, 3, false},
+		{"4xx is not retried", []int{http.StatusNotFound}, nil, 1, true},
+		{"retries run out", []int{http.StatusServiceUnavailable}, &two, 3, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			notifier := newTestNotifier(t)
+			target := newWebhookTarget(t, test.statuses...)
+			useServers(t, map[string]ConfigServer{"alpha": {Webhooks: []WebhookConfig{
+				{URL: target.URL + "/hooks/secret-token", Format: "discord", Retries: test.retries},
+			}}})
+
+			notifier.deliver(joinEvent)
+			if got := len(target.received()); got != test.requests {
+				t.Errorf("%d requests, want %d", got, test.requests)
+			}
+
+			letters := readDeadLetters(t)
+			if !test.failed {
+				if len(letters) != 0 {
+					t.Errorf("dead letters %v, want none", letters)
+				}
+				return
+			}
+			if len(letters) != 1 {
+				t.Fatalf("%d dead letters, want 1", len(letters))
+			}
+			letter := letters[0]
+			if letter["server"] != "alpha" || letter["attempts"] != float64(test.requests) || letter["body"] != `{"content": "Alice \"A\" joined alpha"}` {
+				t.Errorf("dead letter %v", letter)
+			}
+			if host := strings.TrimPrefix(target.URL, "http://"); letter["host"] != host {
+				t.Errorf("dead letter host %v, want %s", letter["host"], host)
+			}
+			if line, _ := json.Marshal(letter); strings.Contains(string(line), "secret-token") {
+				t.Errorf("dead letter leaks the webhook URL: %s", line)
+			}
+		})
+	}
+}
+
+func TestWebhookUnreachable(t *testing.T) {
+	notifier := newTestNotifier(t)
+	target := newWebhookTarget(t)
+	target.Close()
+	zero := 0
+	useServers(t, map[string]ConfigServer{"alpha": {Webhooks: []WebhookConfig{
+		{URL: target.URL + "/hooks/secret-token", Retries: &zero},
+	}}})
+
+	notifier.deliver(joinEvent)
+	letters := readDeadLetters(t)
+	if len(letters) != 1 || letters[0]["attempts"] != float64(1) {
+		t.Fatalf("dead letters %v, want one after 1 attempt", letters)
+	}
+	if message, _ := letters[0]["error"].(string); message == "" || strings.Contains(message, "secret-token") {
+		t.Errorf("dead letter error %q should name the failure without the URL", message)
+	}
+}
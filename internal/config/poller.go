@@ -0,0 +1,173 @@
+package config
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Snapshot is the latest known state of a server.
+type Snapshot struct {
+	Info       *ServerInfo
+	UpdatedAt  time.Time
+	LastOnline time.Time
+	// players is the last player list read from a reachable server,
+	// so an offline blip does not look like everyone leaving and rejoining.
+	players []Player
+}
+
+// SnapshotStore holds the latest snapshot of every polled server.
+type SnapshotStore struct {
+	mu        sync.RWMutex
+	snapshots map[string]Snapshot
+}
+
+// Snapshots is the shared cache the poller writes and the handlers read.
+var Snapshots = &SnapshotStore{snapshots: make(map[string]Snapshot)}
+
+// Get returns the latest snapshot of the named server.
+func (s *SnapshotStore) Get(name string) (Snapshot, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshot, ok := s.snapshots[name]
+	return snapshot, ok
+}
+
+// All returns a copy of every snapshot by server name.
+func (s *SnapshotStore) All() map[string]Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshots := make(map[string]Snapshot, len(s.snapshots))
+	for name, snapshot := range s.snapshots {
+		snapshots[name] = snapshot
+	}
+	return snapshots
+}
+
+// Set stores a fresh ServerInfo for the named server and returns the new snapshot.
+func (s *SnapshotStore) Set(name string, info *ServerInfo) Snapshot {
+	snapshot, complete, changed := s.set(name, info)
+	if changed {
+		Events.Status(name, info, snapshot.UpdatedAt)
+	}
+	// Write the history outside the lock, it touches the disk
+	if complete {
+		History.Record(name, info.Players.List, snapshot.UpdatedAt)
+	} else {
+		History.CloseSessions(name)
+	}
+	return snapshot
+}
+
+// set stores the snapshot and reports whether it holds a complete player list
+// and whether the status differs from the previous snapshot.
+func (s *SnapshotStore) set(name string, info *ServerInfo) (Snapshot, bool, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	previous, seen := s.snapshots[name]
+	snapshot := Snapshot{Info: info, UpdatedAt: now, LastOnline: previous.LastOnline, players: previous.players}
+	if info.Online {
+		snapshot.LastOnline = now
+	}
+	if seen && previous.Info.Online != info.Online {
+		eventType := EventServerOffline
+		if info.Online {
+			eventType = EventServerOnline
+		}
+		Events.Record(name, eventType, nil, now)
+	}
+	// Only a complete answer says anything about who is online
+	complete := info.Online && info.Error == ""
+	if complete {
+		if seen && previous.players != nil {
+			recordPlayerChanges(name, previous.players, info.Players.List, now)
+		}
+		snapshot.players = info.Players.List
+	}
+	s.snapshots[name] = snapshot
+	changed := !seen || statusChanged(previous.Info, info)
+	return snapshot, complete, changed
+}
+
+// statusChanged reports whether a poll result differs from the previous one in what
+// clients follow: whether the server is up, its name and version, who is online and
+// the kind of error. Error texts are left out, they count down to the next retry.
+func statusChanged(previous *ServerInfo, info *ServerInfo) bool {
+	if previous.Online != info.Online || previous.Name != info.Name || previous.Version != info.Version ||
+		previous.ErrorCode != info.ErrorCode || previous.Players.Count != info.Players.Count ||
+		len(previous.Players.List) != len(info.Players.List) {
+		return true
+	}
+	for i, player := range info.Players.List {
+		if playerKey(player) != playerKey(previous.Players.List[i]) || player.Name != previous.Players.List[i].Name {
+			return true
+		}
+	}
+	return false
+}
+
+// Retain drops snapshots of servers that are no longer configured.
+func (s *SnapshotStore) Retain(servers map[string]ConfigServer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name := range s.snapshots {
+		if _, ok := servers[name]; !ok {
+			delete(s.snapshots, name)
+		}
+	}
+}
+
+// StartPoller polls every configured server each Config.PollInterval, and after every
+// config reload, until ctx is done.
+// A zero interval disables polling, and handlers then always query live.
+func StartPoller(ctx context.Context) {
+	interval := Config.PollInterval
+	if interval <= 0 {
+		log.Println("Poller disabled, every request queries the servers live")
+		return
+	}
+
+	// Poll right away after a config change, so new servers don't wait for the next tick
+	changed := make(chan struct{}, 1)
+	ServerConfigs.OnChange(func(map[string]ConfigServer) {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+
+	log.Printf("Polling servers every %s", interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			pollServers(ctx)
+			select {
+			case <-ticker.C:
+			case <-changed:
+				ticker.Reset(interval)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// pollServers queries every configured server once and stores the results.
+func pollServers(ctx context.Context) {
+	servers, err := GetConfig()
+	if err != nil {
+		log.Println("Poller failed to read server configurations:", err)
+		return
+	}
+	Snapshots.Retain(servers)
+	Breakers.Retain(servers)
+
+	ctx, cancel := context.WithTimeout(ctx, Config.RconDeadline)
+	defer cancel()
+	for name, info := range GetAllRconData(ctx, servers) {
+		Snapshots.Set(name, info)
+	}
+}
@@ -0,0 +1,39 @@
+package config
+
+import "testing"
+
+func TestSnapshotStoreStatusChanges(t *testing.T) {
+	alice := Player{Name: "Alice", PID: "1111", SID: "76561198000000001", Ping: 20}
+	bob := Player{Name: "Bob", PID: "2222", SID: "76561198000000002"}
+	online := func(players ...Player) *ServerInfo {
+		return &ServerInfo{Online: true, Name: "Alpha", Version: "v0.3.1", Players: Players{Count: len(players), List: players}}
+	}
+	offline := func(message string, code string) *ServerInfo {
+		return &ServerInfo{Error: message, ErrorCode: code, Players: Players{List: []Player{}}}
+	}
+	moved := alice
+	moved.Ping, moved.LocationX = 80, 1234
+
+	tests := []struct {
+		name    string
+		info    *ServerInfo
+		changed bool
+	}{
+		{"first poll", online(alice), true},
+		{"same", online(alice), false},
+		{"player stats", online(moved), false},
+		{"player joined", online(alice, bob), true},
+		{"player renamed", online(alice, Player{Name: "Robert", PID: "2222", SID: "76561198000000002"}), true},
+		{"offline", offline("rcon: next reconnect to alpha in 4s, last attempt failed: refused", ErrCodeDial), true},
+		{"retry countdown", offline("rcon: next reconnect to alpha in 2s, last attempt failed: refused", ErrCodeDial), false},
+		{"other error", offline("circuit breaker open, retry in 30s", ErrCodeCircuitOpen), true},
+		{"breaker countdown", offline("circuit breaker open, retry in 29s", ErrCodeCircuitOpen), false},
+		{"back online", online(alice), true},
+	}
+	store := &SnapshotStore{snapshots: make(map[string]Snapshot)}
+	for _, test := range tests {
+		if _, _, changed := store.set("status-test", test.info); changed != test.changed {
+			t.Errorf("%s: changed = %v, want %v", test.name, changed, test.changed)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorcon/rcon"
+)
+
+// Connection pool constants
+var PoolConfig = struct {
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	RetryBackoff time.Duration
+	BackoffBase  time.Duration
+	BackoffMax   time.Duration
+	KeepAlive    time.Duration
+	IdleTimeout  time.Duration
+}{
+	DialTimeout:  5 * time.Second, // defaults of the per-server settings in rcon.yaml
+	ReadTimeout:  5 * time.Second,
+	RetryBackoff: 500 * time.Millisecond,
+	BackoffBase:  1 * time.Second,
+	BackoffMax:   60 * time.Second,
+	KeepAlive:    30 * time.Second, // ping connections that have been idle this long
+	IdleTimeout:  5 * time.Minute,  // close connections that have been idle this long
+}
+
+// rconConn is a single authenticated connection to one server.
+// Its mutex serializes commands, since RCON answers are matched by order.
+type rconConn struct {
+	mu          sync.Mutex
+	address     string
+	password    string        // settings of the last dial, successful or not
+	readTimeout time.Duration
+	dialTimeout time.Duration
+	conn        *rcon.Conn
+	lastUsed    time.Time
+	failures    int
+	retryAt     time.Time
+	lastErr     error // error of the last failed dial
+}
+
+// ConnPool keeps one reusable RCON connection per server address.
+type ConnPool struct {
+	mu    sync.Mutex
+	conns map[string]*rconConn
+	once  sync.Once
+}
+
+// Pool is the shared RCON connection pool used by sendCommand.
+var Pool = &ConnPool{conns: make(map[string]*rconConn)}
+
+// Execute runs command on the server, dialing or reusing a pooled connection.
+// A reused connection that turns out to be broken is replaced once before giving up,
+// for status commands only: an admin command may have reached the server before the error.
+// Failed dials are not tried again before the reconnect backoff is over, retries included.
+func (p *ConnPool) Execute(configServer ConfigServer, command string) (string, error) {
+	p.once.Do(func() { go p.keepAlive() })
+
+	c := p.get(configServer.Address)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Credentials or deadlines changed in the config, the old session and failures no longer count
+	if c.settingsChanged(configServer) {
+		c.reset()
+	}
+
+	for {
+		reused := c.conn != nil
+		if !reused {
+			if err := c.dial(configServer); err != nil {
+				return "", err
+			}
+		}
+
+		response, err := c.conn.Execute(command)
+		if err == nil {
+			c.lastUsed = time.Now()
+			return response, nil
+		}
+
+		c.close()
+		if !reused || !isStatusCommand(command) {
+			return "", err
+		}
+		log.Printf("RCON connection to %s is broken, reconnecting: %v", c.address, err)
+	}
+}
+
+// Close closes every pooled connection.
+func (p *ConnPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for address, c := range p.conns {
+		c.mu.Lock()
+		c.close()
+		c.mu.Unlock()
+		delete(p.conns, address)
+	}
+}
+
+// Retain closes connections to addresses no RCON server uses anymore, and sessions
+// whose password or timeouts changed, once the config is reloaded. Servers waiting to
+// reconnect with the old settings may try again right away.
+func (p *ConnPool) Retain(servers map[string]ConfigServer) {
+	byAddress := make(map[string]ConfigServer, len(servers))
+	for _, server := range servers {
+		if server.Type == "" || server.Type == BackendRcon {
+			byAddress[server.Address] = server
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for address, c := range p.conns {
+		server, ok := byAddress[address]
+		c.mu.Lock()
+		if !ok || c.settingsChanged(server) {
+			if c.conn != nil {
+				log.Printf("Closing RCON connection to %s after a config change", address)
+			}
+			c.reset()
+		}
+		c.mu.Unlock()
+		if !ok {
+			delete(p.conns, address)
+		}
+	}
+}
+
+func (p *ConnPool) get(address string) *rconConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c, ok := p.conns[address]
+	if !ok {
+		c = &rconConn{address: address}
+		p.conns[address] = c
+	}
+	return c
+}
+
+// keepAlive pings idle connections so dead ones are noticed before a request needs them,
+// and closes connections nobody has used for a while.
+func (p *ConnPool) keepAlive() {
+	ticker := time.NewTicker(PoolConfig.KeepAlive)
+	defer ticker.Stop()
+	for range ticker.C {
+		p.mu.Lock()
+		conns := make([]*rconConn, 0, len(p.conns))
+		for _, c := range p.conns {
+			conns = append(conns, c)
+		}
+		p.mu.Unlock()
+
+		for _, c := range conns {
+			// Skip connections that are busy running a command
+			if !c.mu.TryLock() {
+				continue
+			}
+			if c.conn != nil {
+				idle := time.Since(c.lastUsed)
+				if idle >= PoolConfig.IdleTimeout {
+					log.Printf("Closing idle RCON connection to %s", c.address)
+					c.close()
+				} else if idle >= PoolConfig.KeepAlive {
+					if _, err := c.conn.Execute(Rcon.Command.Info); err != nil {
+						log.Printf("RCON keepalive to %s failed: %v", c.address, err)
+						c.close()
+					}
+				}
+			}
+			c.mu.Unlock()
+		}
+	}
+}
+
+// dial opens and authenticates a new connection, backing off exponentially after failures.
+func (c *rconConn) dial(configServer ConfigServer) error {
+	if wait := time.Until(c.retryAt); wait > 0 {
+		err := fmt.Errorf("rcon: next reconnect to %s in %s, last attempt failed: %w", c.address, wait.Round(time.Millisecond), c.lastErr)
+		return &ServerError{Code: errorCode(c.lastErr), Err: &backoffError{err}}
+	}
+
+	c.password = configServer.Password
+	c.readTimeout = configServer.ReadTimeoutDuration()
+	c.dialTimeout = configServer.DialTimeoutDuration()
+	conn, err := rcon.Dial(c.address, c.password,
+		rcon.SetDialTimeout(c.dialTimeout),
+		rcon.SetDeadline(c.readTimeout))
+	if err != nil {
+		c.failures++
+		backoff := PoolConfig.BackoffBase << (c.failures - 1)
+		if backoff > PoolConfig.BackoffMax || backoff <= 0 {
+			backoff = PoolConfig.BackoffMax
+		}
+		c.retryAt = time.Now().Add(backoff)
+		c.lastErr = err
+		log.Printf("Error connecting to RCON server %s (attempt %d, retry in %s): %v", c.address, c.failures, backoff, err)
+		return &backoffError{err}
+	}
+
+	c.conn = conn
+	c.lastUsed = time.Now()
+	c.failures = 0
+	c.retryAt = time.Time{}
+	c.lastErr = nil
+	return nil
+}
+
+// backoffError is a failed dial, or one skipped because the server waits to reconnect.
+// The pool decides when to dial again, so retrying it right away is pointless.
+type backoffError struct {
+	err error
+}
+
+func (e *backoffError) Error() string {
+	return e.err.Error()
+}
+
+func (e *backoffError) Unwrap() error {
+	return e.err
+}
+
+// settingsChanged reports whether the server's settings differ from the last dial's.
+func (c *rconConn) settingsChanged(configServer ConfigServer) bool {
+	return c.password != configServer.Password || c.readTimeout != configServer.ReadTimeoutDuration() ||
+		c.dialTimeout != configServer.DialTimeoutDuration()
+}
+
+// reset closes the connection and forgets earlier dial failures.
+func (c *rconConn) reset() {
+	c.close()
+	c.failures = 0
+	c.retryAt = time.Time{}
+	c.lastErr = nil
+}
+
+func (c *rconConn) close() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
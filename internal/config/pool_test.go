@@ -0,0 +1,138 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"palworld-query-api/internal/rcontest"
+)
+
+// newTestPool is an empty pool, so tests don't share connections.
+func newTestPool() *ConnPool {
+	return &ConnPool{conns: make(map[string]*rconConn)}
+}
+
+// countCommands counts the commands the fake server received that start with prefix.
+func countCommands(server *rcontest.Server, prefix string) int {
+	count := 0
+	for _, command := range server.Commands() {
+		if strings.HasPrefix(strings.ToLower(command), strings.ToLower(prefix)) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestPoolReusesConnection(t *testing.T) {
+	server := startServer(t, rcontest.Config{Password: "secret"})
+	pool := newTestPool()
+	defer pool.Close()
+	configServer := ConfigServer{Name: "test", Address: server.Addr(), Password: "secret"}
+
+	for i := 0; i < 3; i++ {
+		if _, err := pool.Execute(configServer, Rcon.Command.Info); err != nil {
+			t.Fatalf("Execute %d: %v", i, err)
+		}
+	}
+	if got := len(pool.conns); got != 1 {
+		t.Fatalf("pool has %d connections, want 1", got)
+	}
+}
+
+func TestPoolBrokenConnection(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		sent    int // times the command reaches the server
+	}{
+		{"status command is resent on a new connection", Rcon.Command.Info, 2},
+		{"admin command is sent once", "Broadcast hi_there", 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := startServer(t, rcontest.Config{Password: "secret"})
+			pool := newTestPool()
+			defer pool.Close()
+			configServer := ConfigServer{Name: "test", Address: server.Addr(), Password: "secret"}
+
+			// Open the connection, then break every answer
+			if _, err := pool.Execute(configServer, Rcon.Command.ShowPlayers); err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			server.SetFaults(rcontest.Faults{Truncate: true})
+
+			if _, err := pool.Execute(configServer, test.command); err == nil {
+				t.Fatalf("Execute %q succeeded on a broken connection", test.command)
+			}
+			if got := countCommands(server, test.command); got != test.sent {
+				t.Fatalf("%q reached the server %d times, want %d: %q", test.command, got, test.sent, server.Commands())
+			}
+		})
+	}
+}
+
+// startServer starts a fake Palworld server that is closed when the test ends.
+func startServer(t *testing.T, config rcontest.Config) *rcontest.Server {
+	t.Helper()
+	server, err := rcontest.NewServer(config)
+	if err != nil {
+		t.Fatalf("starting fake server: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+func TestPoolRetainResetsBackoff(t *testing.T) {
+	server := startServer(t, rcontest.Config{Password: "secret"})
+	pool := newTestPool()
+	defer pool.Close()
+	wrong := ConfigServer{Name: "test", Address: server.Addr(), Password: "wrong"}
+
+	// A refused password puts the server into reconnect backoff
+	for i := 0; i < 2; i++ {
+		if _, err := pool.Execute(wrong, Rcon.Command.Info); err == nil {
+			t.Fatal("Execute with the wrong password succeeded")
+		}
+	}
+	if got := countCommands(server, Rcon.Command.Info); got != 0 {
+		t.Fatalf("%d commands reached the server, want none", got)
+	}
+
+	tests := []struct {
+		name   string
+		server ConfigServer
+		reset  bool
+	}{
+		{"same settings", wrong, false},
+		{"fixed password", ConfigServer{Name: "test", Address: server.Addr(), Password: "secret"}, true},
+		{"changed timeout", ConfigServer{Name: "test", Address: server.Addr(), Password: "wrong", Timeout: "2s"}, true},
+		{"removed", ConfigServer{Name: "test", Address: "127.0.0.1:1", Password: "wrong"}, true},
+	}
+	for _, test := range tests {
+		c := pool.get(server.Addr())
+		c.mu.Lock()
+		c.password, c.readTimeout, c.dialTimeout = wrong.Password, wrong.ReadTimeoutDuration(), wrong.DialTimeoutDuration()
+		c.failures, c.retryAt, c.lastErr = 3, time.Now().Add(time.Minute), errors.New("authentication failed")
+		c.mu.Unlock()
+
+		pool.Retain(map[string]ConfigServer{"test": test.server})
+		c.mu.Lock()
+		reset := c.failures == 0 && c.retryAt.IsZero() && c.lastErr == nil
+		c.mu.Unlock()
+		if reset != test.reset {
+			t.Errorf("%s: backoff reset = %v, want %v", test.name, reset, test.reset)
+		}
+	}
+
+	// After fixing the password, the next command is sent without waiting for the backoff
+	right := ConfigServer{Name: "test", Address: server.Addr(), Password: "secret"}
+	if _, err := pool.Execute(wrong, Rcon.Command.Info); err == nil {
+		t.Fatal("Execute with the wrong password succeeded")
+	}
+	pool.Retain(map[string]ConfigServer{"test": right})
+	if _, err := pool.Execute(right, Rcon.Command.Info); err != nil {
+		t.Fatalf("Execute after fixing the password: %v", err)
+	}
+}
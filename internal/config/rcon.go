@@ -5,23 +5,44 @@ import (
     "log"
     "unicode"
 	"unicode/utf8"
-	"github.com/gorcon/rcon"
-    "fmt"
     "errors"
-	"time"
+    "time"
 )
 
 type ServerInfo struct {
-    Online    bool    `json:"online"`
-    Name      string  `json:"serverName"`
-    Version   string  `json:"serverVer"`
-    Players   Players `json:"players"`
+    Online      bool           `json:"online"`
+    Name        string         `json:"serverName"`
+    Version     string         `json:"serverVer"`
+    Description string         `json:"description,omitempty"`
+    WorldGUID   string         `json:"worldGuid,omitempty"`
+    Players     Players        `json:"players"`
+    Metrics     *ServerMetrics `json:"metrics,omitempty"`
+    Error       string         `json:"error,omitempty"`
+    ErrorCode   string         `json:"errorCode,omitempty"`
+    Breaker     *BreakerState  `json:"breaker,omitempty"` // added when serving, not part of the snapshot
+    Meta        *ServerMeta    `json:"meta,omitempty"`    // added when serving, from rcon.yaml
 }
 
+// ServerMetrics are only reported by servers using the REST backend.
+type ServerMetrics struct {
+    FPS        int     `json:"serverFps"`
+    FrameTime  float64 `json:"serverFrameTime"`
+    MaxPlayers int     `json:"maxPlayers"`
+    Uptime     int64   `json:"uptime"`
+    Days       int     `json:"days,omitempty"`
+}
+
+// Player fields other than Name, PID and SID are only reported by the REST backend.
 type Player struct {
-    Name string `json:"name"`
-    PID  string `json:"pid"`
-    SID  string `json:"sid"`
+    Name          string  `json:"name"`
+    PID           string  `json:"pid"`
+    SID           string  `json:"sid"`
+    AccountName   string  `json:"accountName,omitempty"`
+    Level         int     `json:"level,omitempty"`
+    Ping          float64 `json:"ping,omitempty"`
+    LocationX     float64 `json:"locationX,omitempty"`
+    LocationY     float64 `json:"locationY,omitempty"`
+    BuildingCount int     `json:"buildingCount,omitempty"`
 }
 
 type Players struct {
@@ -33,6 +54,12 @@ type Players struct {
 type Command struct {
 	Info        string
 	ShowPlayers string
+	Broadcast   string
+	KickPlayer  string
+	BanPlayer   string
+	Save        string
+	Shutdown    string
+	DoExit      string
 }
 
 var Rcon = struct {
@@ -41,10 +68,33 @@ var Rcon = struct {
 	Command: Command{
 		Info:        "info",
 		ShowPlayers: "showplayers",
+		Broadcast:   "broadcast",
+		KickPlayer:  "kickplayer",
+		BanPlayer:   "banplayer",
+		Save:        "save",
+		Shutdown:    "shutdown",
+		DoExit:      "doexit",
 	},
 }
 
+// GetRconData reads the server status through the backend configured for the server.
+// It fails with a *ServerError when the server can't be queried at all; a partial
+// answer is returned with its Error and ErrorCode set instead.
 func GetRconData(configServer ConfigServer) (*ServerInfo, error) {
+    backend, err := NewBackend(configServer)
+    if err != nil {
+        return nil, err
+    }
+    return backend.ServerInfo()
+}
+
+// rconBackend talks to the server over RCON.
+type rconBackend struct {
+    server ConfigServer
+}
+
+func (b *rconBackend) ServerInfo() (*ServerInfo, error) {
+    configServer := b.server
     serverInfo := &ServerInfo{}
     cmdInfo := Rcon.Command.Info
     cmdShowPlayers := Rcon.Command.ShowPlayers
@@ -52,8 +102,9 @@ func GetRconData(configServer ConfigServer) (*ServerInfo, error) {
     infoCommandOutput, err := sendCommand(configServer, cmdInfo)
     if err != nil {
         log.Printf("Error running INFO: %v", err)
+        // The server is unreachable, so there is no point in asking for players
+        return nil, err
     }
-    log.Printf("infoCommandOutput: %v", infoCommandOutput)
 
     // Parse server version and name
     serverInfo.Version = ParseRconVersion(infoCommandOutput)
@@ -68,9 +119,10 @@ func GetRconData(configServer ConfigServer) (*ServerInfo, error) {
 
     playersCommandOutput, err := sendCommand(configServer, cmdShowPlayers)
     if err != nil {
-        log.Printf("Error running INFO: %v", err)
+        log.Printf("Error running SHOWPLAYERS: %v", err)
+        serverInfo.Error = Redact(err.Error())
+        serverInfo.ErrorCode = AsServerError(configServer.Name, err).Code
     }
-    log.Printf("playersCommandOutput: %v", playersCommandOutput)
 
     // Parse player list
     count, players := ParsePlayerList(playersCommandOutput)
@@ -180,27 +232,32 @@ func ParsePlayerList(input string) (int, []Player) {
 
 func sendCommand(configServer ConfigServer, command string) (string, error) {
 	if configServer.Address == "" {
-		return "", errors.New("RCON server address is empty")
+		return "", &ServerError{Code: ErrCodeConfig, Server: configServer.Name, Err: errors.New("RCON server address is empty")}
 	}
 	if configServer.Password == "" {
-		return "", errors.New("RCON server password is empty")
+		return "", &ServerError{Code: ErrCodeConfig, Server: configServer.Name, Err: errors.New("RCON server password is empty")}
 	}
-    timeoutDur,_ :=  time.ParseDuration("5s")//configServer.Timeout)
-    //timeoutDur,_ :=  time.ParseDuration("5s")//configServer.Timeout)
-    log.Println("timeoutDur:", timeoutDur)
-	conn, err := rcon.Dial(configServer.Address, configServer.Password, rcon.SetDialTimeout(timeoutDur))
-	if err != nil {
-		log.Println("Error connecting to RCON server:", err)
-		return "", err
+	var response string
+	execute := func() error {
+		start := time.Now()
+		output, err := Pool.Execute(configServer, command)
+		observeCommand(configServer.Name, BackendRcon, command, start, err)
+		response = output
+		return err
 	}
-	defer conn.Close()
 
-	response, err := conn.Execute(command)
+	// Admin commands may have run before failing, so only status queries are retried
+	var err error
+	err = withBreaker(configServer, func() error {
+		if isStatusCommand(command) {
+			return withRetries(configServer, command, execute)
+		}
+		return execute()
+	})
 	if err != nil {
 		log.Println("Error executing command:", err)
-		return "", err
+		return "", AsServerError(configServer.Name, err)
 	}
 
-	fmt.Println(response)
 	return string(response), nil // Convert output to string before returning
 }
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"palworld-query-api/internal/rcontest"
+)
+
+var testPlayers = []rcontest.Player{
+	{Name: "Alice", PlayerUID: "1111", SteamID: "76561198000000001"},
+	{Name: "Bob Builder", PlayerUID: "2222", SteamID: "76561198000000002"},
+}
+
+// wantPlayers is testPlayers as ParsePlayerList returns them.
+var wantPlayers = []Player{
+	{Name: "Alice", PID: "1111", SID: "76561198000000001"},
+	{Name: "Bob Builder", PID: "2222", SID: "76561198000000002"},
+}
+
+func TestParseRconVersionAndName(t *testing.T) {
+	tests := []struct {
+		input   string
+		version string
+		name    string
+	}{
+		{"Welcome to Pal Server[v0.1.5.1] My Server\n", "v0.1.5.1", "My Server"},
+		{"Welcome to Pal Server[v0.1.5.1] My Server", "v0.1.5.1", "My Server"},
+		{"Welcome to Pal Server[v0.1.5.1] My Server\x00\x00\n", "v0.1.5.1", "My Server"},
+		{"garbage", "", ""},
+		{"", "", ""},
+	}
+	for _, test := range tests {
+		version := ParseRconVersion(test.input)
+		if version != test.version {
+			t.Errorf("ParseRconVersion(%q) = %q, want %q", test.input, version, test.version)
+		}
+		if name := ParseRconName(version, test.input); name != test.name {
+			t.Errorf("ParseRconName(%q, %q) = %q, want %q", version, test.input, name, test.name)
+		}
+	}
+}
+
+func TestParsePlayerList(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		players []Player
+	}{
+		{"no players", "name,playeruid,steamid\n", []Player{}},
+		{"empty answer", "", []Player{}},
+		{"players", "name,playeruid,steamid\nAlice,1111,76561198000000001\nBob Builder,2222,76561198000000002\n", wantPlayers},
+		{"malformed lines are skipped", "name,playeruid,steamid\nAlice,1111,76561198000000001\nbroken\n\x00\x00\n", wantPlayers[:1]},
+	}
+	for _, test := range tests {
+		count, players := ParsePlayerList(test.input)
+		if count != len(test.players) || !reflect.DeepEqual(players, test.players) {
+			t.Errorf("%s: ParsePlayerList = %d, %v, want %d, %v", test.name, count, players, len(test.players), test.players)
+		}
+	}
+}
+
+// TestParsersAgainstFakeServer parses the answers of the fake server, as the backend does.
+func TestParsersAgainstFakeServer(t *testing.T) {
+	for _, nullBytes := range []bool{false, true} {
+		server := startServer(t, rcontest.Config{
+			Password: "secret",
+			Name:     "Parser Test",
+			Version:  "v0.3.1.2",
+			Players:  testPlayers,
+			Faults:   rcontest.Faults{NullBytes: nullBytes},
+		})
+		pool := newTestPool()
+		configServer := ConfigServer{Name: "parser", Address: server.Addr(), Password: "secret"}
+
+		info, err := pool.Execute(configServer, Rcon.Command.Info)
+		if err != nil {
+			t.Fatalf("null bytes %v: info: %v", nullBytes, err)
+		}
+		version := ParseRconVersion(info)
+		if name := ParseRconName(version, info); version != "v0.3.1.2" || name != "Parser Test" {
+			t.Errorf("null bytes %v: parsed version %q and name %q from %q", nullBytes, version, name, info)
+		}
+
+		list, err := pool.Execute(configServer, Rcon.Command.ShowPlayers)
+		if err != nil {
+			t.Fatalf("null bytes %v: showplayers: %v", nullBytes, err)
+		}
+		if count, players := ParsePlayerList(list); count != 2 || !reflect.DeepEqual(players, wantPlayers) {
+			t.Errorf("null bytes %v: parsed %d players %v from %q", nullBytes, count, players, list)
+		}
+		pool.Close()
+	}
+}
+
+func TestGetRconData(t *testing.T) {
+	tests := []struct {
+		name    string
+		faults  rcontest.Faults
+		code    string // error code GetRconData fails with, empty when it succeeds
+		players []Player
+	}{
+		{name: "online", players: wantPlayers},
+		{name: "null bytes", faults: rcontest.Faults{NullBytes: true}, players: wantPlayers},
+		{name: "auth failure", faults: rcontest.Faults{AuthFailure: true}, code: ErrCodeAuth},
+		{name: "truncated answer", faults: rcontest.Faults{Truncate: true}, code: ErrCodeUpstream},
+		{name: "hang", faults: rcontest.Faults{Hang: true}, code: ErrCodeTimeout},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := startServer(t, rcontest.Config{Password: "secret", Players: testPlayers, Faults: test.faults})
+			configServer := ConfigServer{
+				Name:     "getrcondata " + test.name,
+				Address:  server.Addr(),
+				Password: "secret",
+				Timeout:  "200ms",
+			}
+
+			info, err := GetRconData(configServer)
+			if test.code != "" {
+				var serverErr *ServerError
+				if !errors.As(err, &serverErr) || serverErr.Code != test.code {
+					t.Fatalf("GetRconData = %v, %v, want a %s error", info, err, test.code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetRconData: %v", err)
+			}
+			if !info.Online || info.Name != "Fake Palworld Server" || info.Version != "v0.1.5.1" {
+				t.Errorf("GetRconData = online %v, name %q, version %q", info.Online, info.Name, info.Version)
+			}
+			if info.Players.Count != len(test.players) || !reflect.DeepEqual(info.Players.List, test.players) {
+				t.Errorf("GetRconData players = %d, %v, want %v", info.Players.Count, info.Players.List, test.players)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Palworld REST API constants
+var RestConfig = struct {
+	BasePath        string
+	DefaultUsername string
+	Info            string
+	Players         string
+	Metrics         string
+	Announce        string
+	Kick            string
+	Ban             string
+	Save            string
+	Shutdown        string
+	Stop            string
+}{
+	BasePath:        "/v1/api",
+	DefaultUsername: "admin",
+	Info:            "/info",
+	Players:         "/players",
+	Metrics:         "/metrics",
+	Announce:        "/announce",
+	Kick:            "/kick",
+	Ban:             "/ban",
+	Save:            "/save",
+	Shutdown:        "/shutdown",
+	Stop:            "/stop",
+}
+
+type restInfo struct {
+	Version     string `json:"version"`
+	ServerName  string `json:"servername"`
+	Description string `json:"description"`
+	WorldGUID   string `json:"worldguid"`
+}
+
+type restPlayer struct {
+	Name          string  `json:"name"`
+	AccountName   string  `json:"accountName"`
+	PlayerID      string  `json:"playerId"`
+	UserID        string  `json:"userId"`
+	Ping          float64 `json:"ping"`
+	LocationX     float64 `json:"location_x"`
+	LocationY     float64 `json:"location_y"`
+	Level         int     `json:"level"`
+	BuildingCount int     `json:"building_count"`
+}
+
+type restPlayers struct {
+	Players []restPlayer `json:"players"`
+}
+
+type restMetrics struct {
+	ServerFPS        int     `json:"serverfps"`
+	CurrentPlayerNum int     `json:"currentplayernum"`
+	ServerFrameTime  float64 `json:"serverframetime"`
+	MaxPlayerNum     int     `json:"maxplayernum"`
+	Uptime           int64   `json:"uptime"`
+	Days             int     `json:"days"`
+}
+
+// restBackend talks to the server over the official REST API with basic auth.
+type restBackend struct {
+	server ConfigServer
+}
+
+// Each request gets the server's own timeouts, see do
+var restClient = &http.Client{}
+
+func (b *restBackend) ServerInfo() (*ServerInfo, error) {
+	serverInfo := &ServerInfo{Players: Players{List: []Player{}}}
+
+	var info restInfo
+	if err := b.do(http.MethodGet, RestConfig.Info, nil, &info); err != nil {
+		log.Printf("Error reading REST info: %v", err)
+		return nil, err
+	}
+	serverInfo.Name = strings.TrimSpace(info.ServerName)
+	serverInfo.Version = info.Version
+	serverInfo.Description = info.Description
+	serverInfo.WorldGUID = info.WorldGUID
+	serverInfo.Online = serverInfo.Name != "" && serverInfo.Version != ""
+
+	var players restPlayers
+	if err := b.do(http.MethodGet, RestConfig.Players, nil, &players); err != nil {
+		log.Printf("Error reading REST players: %v", err)
+		serverInfo.Error = Redact(err.Error())
+		serverInfo.ErrorCode = AsServerError(b.server.Name, err).Code
+	}
+	for _, player := range players.Players {
+		serverInfo.Players.List = append(serverInfo.Players.List, Player{
+			Name:          player.Name,
+			PID:           player.PlayerID,
+			SID:           strings.TrimPrefix(player.UserID, "steam_"),
+			AccountName:   player.AccountName,
+			Level:         player.Level,
+			Ping:          player.Ping,
+			LocationX:     player.LocationX,
+			LocationY:     player.LocationY,
+			BuildingCount: player.BuildingCount,
+		})
+	}
+	serverInfo.Players.Count = len(serverInfo.Players.List)
+
+	// Metrics are a bonus, the status is complete without them
+	var metrics restMetrics
+	if err := b.do(http.MethodGet, RestConfig.Metrics, nil, &metrics); err != nil {
+		log.Printf("Error reading REST metrics: %v", err)
+	} else {
+		serverInfo.Metrics = &ServerMetrics{
+			FPS:        metrics.ServerFPS,
+			FrameTime:  metrics.ServerFrameTime,
+			MaxPlayers: metrics.MaxPlayerNum,
+			Uptime:     metrics.Uptime,
+			Days:       metrics.Days,
+		}
+	}
+	return serverInfo, nil
+}
+
+func (b *restBackend) Broadcast(message string) (*CommandResult, error) {
+	return b.runAdminCommand(Rcon.Command.Broadcast, RestConfig.Announce, map[string]interface{}{"message": message})
+}
+
+func (b *restBackend) KickPlayer(steamID string) (*CommandResult, error) {
+	result, err := b.runAdminCommand(Rcon.Command.KickPlayer, RestConfig.Kick, map[string]interface{}{"userid": restUserID(steamID)})
+	if err != nil {
+		return nil, err
+	}
+	result.SteamID = steamID
+	return result, nil
+}
+
+func (b *restBackend) BanPlayer(steamID string) (*CommandResult, error) {
+	result, err := b.runAdminCommand(Rcon.Command.BanPlayer, RestConfig.Ban, map[string]interface{}{"userid": restUserID(steamID)})
+	if err != nil {
+		return nil, err
+	}
+	result.SteamID = steamID
+	return result, nil
+}
+
+func (b *restBackend) Save() (*CommandResult, error) {
+	return b.runAdminCommand(Rcon.Command.Save, RestConfig.Save, nil)
+}
+
+func (b *restBackend) Shutdown(seconds int, message string) (*CommandResult, error) {
+	result, err := b.runAdminCommand(Rcon.Command.Shutdown, RestConfig.Shutdown, map[string]interface{}{"waittime": seconds, "message": message})
+	if err != nil {
+		return nil, err
+	}
+	result.Seconds = seconds
+	return result, nil
+}
+
+func (b *restBackend) DoExit() (*CommandResult, error) {
+	return b.runAdminCommand(Rcon.Command.DoExit, RestConfig.Stop, nil)
+}
+
+// runAdminCommand posts to an admin endpoint. The REST API answers with an empty
+// 200 on success, so any error status other than refused credentials is reported
+// as a failed command.
+func (b *restBackend) runAdminCommand(name string, endpoint string, body interface{}) (*CommandResult, error) {
+	err := b.do(http.MethodPost, endpoint, body, nil)
+	var statusErr *restStatusError
+	if errors.As(err, &statusErr) && AsServerError(b.server.Name, err).Code != ErrCodeAuth {
+		return &CommandResult{Command: name, Success: false, Message: statusErr.Error()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &CommandResult{Command: name, Success: true, Message: "OK"}, nil
+}
+
+// restStatusError is returned when the REST API answers with a non-2xx status.
+type restStatusError struct {
+	Status int
+	Body   string
+}
+
+func (e *restStatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("rest: %d %s", e.Status, http.StatusText(e.Status))
+	}
+	return fmt.Sprintf("rest: %d %s", e.Status, e.Body)
+}
+
+// do sends a request to the REST API and decodes the JSON answer into out, if given.
+// Reads are retried as configured for the server, admin commands are sent once.
+func (b *restBackend) do(method string, endpoint string, body interface{}, out interface{}) error {
+	if b.server.Address == "" {
+		return &ServerError{Code: ErrCodeConfig, Server: b.server.Name, Err: errors.New("REST server address is empty")}
+	}
+	if b.server.Password == "" {
+		return &ServerError{Code: ErrCodeConfig, Server: b.server.Name, Err: errors.New("REST server password is empty")}
+	}
+
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	attempt := func() error {
+		return b.send(method, endpoint, data, out)
+	}
+	return withBreaker(b.server, func() error {
+		if method == http.MethodGet {
+			return withRetries(b.server, method+" "+endpoint, attempt)
+		}
+		return attempt()
+	})
+}
+
+// send makes one request, which may take the server's timeout to connect plus its
+// read timeout to answer.
+func (b *restBackend) send(method string, endpoint string, data []byte, out interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), b.server.DialTimeoutDuration()+b.server.ReadTimeoutDuration())
+	defer cancel()
+
+	var reader io.Reader
+	if data != nil {
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL()+endpoint, reader)
+	if err != nil {
+		return err
+	}
+	username := b.server.Username
+	if username == "" {
+		username = RestConfig.DefaultUsername
+	}
+	req.SetBasicAuth(username, b.server.Password)
+	req.Header.Set("Accept", "application/json")
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	start := time.Now()
+	err = sendRequest(req, out)
+	observeCommand(b.server.Name, BackendRest, strings.TrimPrefix(endpoint, "/"), start, err)
+	if err != nil {
+		return AsServerError(b.server.Name, err)
+	}
+	return nil
+}
+
+// sendRequest runs the request and decodes the JSON answer into out, if given.
+func sendRequest(req *http.Request, out interface{}) error {
+	response, err := restClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("rest: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		text, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return &restStatusError{Status: response.StatusCode, Body: strings.TrimSpace(string(text))}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(response.Body).Decode(out); err != nil {
+		return fmt.Errorf("rest: error decoding %s: %w", req.URL.Path, err)
+	}
+	return nil
+}
+
+// baseURL accepts either host:port or a full URL as the server address.
+func (b *restBackend) baseURL() string {
+	address := strings.TrimSuffix(b.server.Address, "/")
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	return address + RestConfig.BasePath
+}
+
+// restUserID converts a Steam ID to the user ID format the REST API expects.
+func restUserID(steamID string) string {
+	if strings.HasPrefix(steamID, "steam_") {
+		return steamID
+	}
+	return "steam_" + steamID
+}
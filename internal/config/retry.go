@@ -0,0 +1,34 @@
+package config
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+// isStatusCommand reports whether command only reads the server's status, so sending
+// it again after an error is harmless. Admin commands may have run before failing.
+func isStatusCommand(command string) bool {
+	return command == Rcon.Command.Info || command == Rcon.Command.ShowPlayers
+}
+
+// withRetries runs attempt until it succeeds, fails with an error that is not worth
+// retrying, or the server's retries are used up. The wait between attempts starts
+// at the server's retry_backoff and doubles each time. Failed RCON dials are left to
+// the connection pool's reconnect backoff.
+func withRetries(configServer ConfigServer, what string, attempt func() error) error {
+	backoff := configServer.RetryBackoffDuration()
+	for try := 0; ; try++ {
+		err := attempt()
+		if err == nil {
+			return nil
+		}
+		var reconnecting *backoffError
+		if try >= configServer.Retries || !AsServerError(configServer.Name, err).Retryable() || errors.As(err, &reconnecting) {
+			return err
+		}
+		log.Printf("Error running %s on %s, retry %d/%d in %s: %v", what, configServer.Name, try+1, configServer.Retries, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
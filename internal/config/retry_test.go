@@ -0,0 +1,63 @@
+package config
+
+import (
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"palworld-query-api/internal/rcontest"
+)
+
+// startDeadServer listens on a local port and closes every connection right away,
+// counting them.
+func startDeadServer(t *testing.T) (string, *atomic.Int32) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	var accepted atomic.Int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted.Add(1)
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String(), &accepted
+}
+
+func TestRetriesWaitForReconnectBackoff(t *testing.T) {
+	address, accepted := startDeadServer(t)
+	configServer := ConfigServer{Name: "dead", Address: address, Password: "secret", Retries: 3, RetryBackoff: "1ms"}
+	t.Cleanup(func() { Pool.Retain(nil) })
+
+	for i := 0; i < 2; i++ {
+		if _, err := sendCommand(configServer, Rcon.Command.Info); err == nil {
+			t.Fatal("sendCommand succeeded against a dead server")
+		} else if i == 1 && !strings.Contains(err.Error(), "next reconnect") {
+			t.Errorf("second sendCommand = %v, want the reconnect backoff", err)
+		}
+	}
+	if got := accepted.Load(); got != 1 {
+		t.Errorf("server was dialed %d times, want once until the backoff is over", got)
+	}
+}
+
+func TestRetriesOnConnectedServer(t *testing.T) {
+	server := startServer(t, rcontest.Config{Password: "secret", Faults: rcontest.Faults{Hang: true}})
+	configServer := ConfigServer{Name: "hanging", Address: server.Addr(), Password: "secret", Timeout: "50ms", Retries: 2, RetryBackoff: "1ms"}
+	t.Cleanup(func() { Pool.Retain(nil) })
+
+	if _, err := sendCommand(configServer, Rcon.Command.Info); err == nil {
+		t.Fatal("sendCommand succeeded against a hanging server")
+	}
+	if got := countCommands(server, Rcon.Command.Info); got != 3 {
+		t.Errorf("info was sent %d times, want 3", got)
+	}
+}
@@ -6,13 +6,48 @@ import (
 // Constants for routes
 var Routes = struct {
 	Index string
+	Servers string
+	Server string
+	Group string
+	PublicSearch string
 	Rcon string
+	RconServer string
 	Api string
 	Health  string
+	Metrics string
+	RconBroadcast string
+	RconKick string
+	RconBan string
+	RconSave string
+	RconShutdown string
+	RconExit string
+	RconEvents string
+	RconPlayersHistory string
+	Player string
+	RconStream string
+	Stream string
 }{
-	Index: "/",
-	Rcon: "/rcon/",
-	Api: "/api",
-	Health:  "/healthz",
+	Index: "GET /{$}",
+	Servers: "GET /v1/servers",
+	Server: "GET /v1/servers/{name}",
+	Group: "GET /v1/groups/{group}",
+	PublicSearch: "GET /v1/public/search",
+	Rcon: "GET /rcon/{$}", // legacy alias of Servers
+	RconServer: "GET /rcon/{name}", // legacy alias of Server
+	Api: "GET /api", // legacy alias of PublicSearch
+	Health:  "GET /healthz",
+	Metrics: "GET /metrics",
+	RconBroadcast: "POST /rcon/{name}/broadcast",
+	RconKick: "POST /rcon/{name}/kick",
+	RconBan: "POST /rcon/{name}/ban",
+	RconSave: "POST /rcon/{name}/save",
+	RconShutdown: "POST /rcon/{name}/shutdown",
+	RconExit: "POST /rcon/{name}/exit",
+	RconEvents: "GET /rcon/{name}/events",
+	RconPlayersHistory: "GET /rcon/{name}/players/history",
+	Player: "GET /players/{steamid}",
+	RconStream: "GET /rcon/{name}/stream",
+	Stream: "GET /stream",
 }
-var RoutesList = []string{Routes.Health,Routes.Rcon, Routes.Api}
+// Paths linked from the index page
+var RoutesList = []string{"/healthz", "/v1/servers", "/v1/public/search", "/rcon/", "/api", "/metrics"}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redacted stands in for secrets in logs and API output.
+const Redacted = "[REDACTED]"
+
+// envReference matches ${NAME} in credential settings. $${NAME} is a literal ${NAME}.
+var envReference = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// secretError is a credential setting that could not be resolved.
+type secretError struct {
+	key string // setting in rcon.yaml, to report its line
+	err error
+}
+
+func (e *secretError) Error() string {
+	return fmt.Sprintf("%s: %v", e.key, e.err)
+}
+
+// resolveSecrets fills in the credentials of the server: the password from password_file
+// or password_env, and ${NAME} references to environment variables in password, username
+// and webhook URLs. Every resolved secret is redacted from logs from then on.
+func (s *ConfigServer) resolveSecrets() error {
+	if countNonEmpty(s.Password, s.PasswordFile, s.PasswordEnv) > 1 {
+		return &secretError{"password", fmt.Errorf("set only one of password, password_file and password_env")}
+	}
+
+	switch {
+	case s.PasswordFile != "":
+		content, err := os.ReadFile(s.PasswordFile)
+		if err != nil {
+			return &secretError{"password_file", err}
+		}
+		s.Password = strings.TrimRight(string(content), "\r\n")
+		if s.Password == "" {
+			return &secretError{"password_file", fmt.Errorf("%s is empty", s.PasswordFile)}
+		}
+	case s.PasswordEnv != "":
+		s.Password = os.Getenv(s.PasswordEnv)
+		if s.Password == "" {
+			return &secretError{"password_env", fmt.Errorf("environment variable %s is not set", s.PasswordEnv)}
+		}
+	default:
+		password, err := expandEnv(s.Password)
+		if err != nil {
+			return &secretError{"password", err}
+		}
+		s.Password = password
+	}
+	Secrets.Add(s.Password)
+
+	username, err := expandEnv(s.Username)
+	if err != nil {
+		return &secretError{"username", err}
+	}
+	s.Username = username
+
+	// Copy the webhooks, the slice is shared with the config the server was decoded from
+	s.Webhooks = append([]WebhookConfig(nil), s.Webhooks...)
+	for i := range s.Webhooks {
+		webhookURL, err := expandEnv(s.Webhooks[i].URL)
+		if err != nil {
+			return &secretError{"webhooks", fmt.Errorf("webhook %d: url: %v", i+1, err)}
+		}
+		s.Webhooks[i].URL = webhookURL
+	}
+	return nil
+}
+
+func countNonEmpty(values ...string) int {
+	count := 0
+	for _, value := range values {
+		if value != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// expandEnv replaces ${NAME} references with the value of the environment variable and
+// adds those values to Secrets. A variable that is not set is an error.
+func expandEnv(value string) (string, error) {
+	var err error
+	expanded := envReference.ReplaceAllStringFunc(value, func(reference string) string {
+		if strings.HasPrefix(reference, "$$") {
+			return reference[1:]
+		}
+		name := envReference.FindStringSubmatch(reference)[1]
+		env, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("environment variable %s is not set", name)
+		}
+		Secrets.Add(env)
+		return env
+	})
+	return expanded, err
+}
+
+// SecretSet is every secret seen in the config. Secrets are kept after a reload
+// removes them, lines about old connections may still mention them.
+type SecretSet struct {
+	mu       sync.RWMutex
+	values   map[string]bool
+	replacer *strings.Replacer
+}
+
+// Secrets is the set Redact and the log output redact.
+var Secrets = &SecretSet{}
+
+// Add adds value to the set. Empty values are ignored, there is nothing to redact.
+func (s *SecretSet) Add(value string) {
+	if value == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values[value] {
+		return
+	}
+	if s.values == nil {
+		s.values = make(map[string]bool)
+	}
+	s.values[value] = true
+
+	// Longer secrets first, so a secret containing another is replaced whole
+	values := make([]string, 0, len(s.values))
+	for secret := range s.values {
+		values = append(values, secret)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	pairs := make([]string, 0, 2*len(values))
+	for _, secret := range values {
+		pairs = append(pairs, secret, Redacted)
+	}
+	s.replacer = strings.NewReplacer(pairs...)
+}
+
+// Redact replaces every secret in text with Redacted.
+func (s *SecretSet) Redact(text string) string {
+	s.mu.RLock()
+	replacer := s.replacer
+	s.mu.RUnlock()
+	if replacer == nil {
+		return text
+	}
+	return replacer.Replace(text)
+}
+
+// Redact replaces every known secret in text with Redacted.
+func Redact(text string) string {
+	return Secrets.Redact(text)
+}
+
+// redactingWriter redacts secrets from everything written to w.
+// The log package writes each line in one call, so secrets are never split.
+type redactingWriter struct {
+	w io.Writer
+}
+
+func (r redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Every log line goes through Redact
+func init() {
+	log.SetOutput(redactingWriter{os.Stderr})
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSecretSetRedactsShortSecrets(t *testing.T) {
+	var secrets SecretSet
+	for _, secret := range []string{"", "Q", "Q7z", "Q7zLongerSecret"} {
+		secrets.Add(secret)
+	}
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"auth failed for Q7z", "auth failed for " + Redacted},
+		{"password Q7zLongerSecret", "password " + Redacted},
+		{"Q", Redacted},
+		{"nothing secret here", "nothing secret here"},
+	}
+	for _, test := range tests {
+		if got := secrets.Redact(test.text); got != test.want {
+			t.Errorf("Redact(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestValidateConfigWarnsAboutShortPasswords(t *testing.T) {
+	problems := ValidateConfig([]byte("alpha:\n  address: 127.0.0.1:25575\n  password: Q7x\n"))
+	if len(problems) != 1 || problems[0].Fatal || problems[0].Line != 3 || !strings.Contains(problems[0].Message, "shorter than 4") {
+		t.Errorf("problems = %v, want a warning about the short password", problems)
+	}
+	if got := Redact("password Q7x"); got != "password "+Redacted {
+		t.Errorf("Redact = %q, the short password is not redacted", got)
+	}
+}
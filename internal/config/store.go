@@ -0,0 +1,202 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay lets a burst of file events, such as an editor's write and rename,
+// settle into one reload.
+const reloadDelay = 250 * time.Millisecond
+
+// errConfigNotLoaded is returned before the first successful load of rcon.yaml.
+var errConfigNotLoaded = errors.New("server config not loaded")
+
+// configState is one loaded version of rcon.yaml. It is never modified once stored.
+type configState struct {
+	servers map[string]ConfigServer
+	raw     []byte
+}
+
+// ConfigStore holds the servers of rcon.yaml. It is loaded once at startup and reloaded
+// when the file changes; a file that fails to parse keeps the last good config in place.
+type ConfigStore struct {
+	path      string
+	state     atomic.Pointer[configState]
+	loadErr   atomic.Pointer[error]
+	rejected  []byte // content of the last file that failed to load, not reported twice
+	reloadMu  sync.Mutex // one reload at a time
+	mu        sync.Mutex
+	listeners []func(map[string]ConfigServer)
+	watcher   *fsnotify.Watcher
+	timer     *time.Timer
+}
+
+// ServerConfigs is the shared store every handler, the poller and the pool read.
+var ServerConfigs = &ConfigStore{}
+
+// Start loads the file at path and watches it for changes. A failed first load is
+// returned, but the file is still watched so fixing it takes effect without a restart.
+// A file that doesn't exist yet means no servers, for deployments that only search
+// the public server list.
+func (s *ConfigStore) Start(path string) error {
+	s.path = path
+	err := s.reload()
+	if watchErr := s.watch(); watchErr != nil {
+		log.Printf("Config hot-reload disabled, error watching %s: %v", path, watchErr)
+	}
+	return err
+}
+
+// Snapshot returns the servers as last loaded. The map is shared and must not be modified.
+// It fails only if the config never loaded successfully.
+func (s *ConfigStore) Snapshot() (map[string]ConfigServer, error) {
+	if state := s.state.Load(); state != nil {
+		return state.servers, nil
+	}
+	if err := s.loadErr.Load(); err != nil {
+		return nil, *err
+	}
+	return nil, errConfigNotLoaded
+}
+
+// OnChange registers listener to be called with the new servers after every successful reload.
+func (s *ConfigStore) OnChange(listener func(map[string]ConfigServer)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// reload reads the file and swaps in the new servers if it changed and is valid.
+func (s *ConfigStore) reload() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	raw, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) && s.state.Load() == nil {
+		log.Printf("Warning: %s does not exist, no servers are configured until it is created", s.path)
+		s.state.Store(&configState{servers: map[string]ConfigServer{}})
+		s.loadErr.Store(nil)
+		return nil
+	}
+	if err == nil && s.state.Load() != nil && bytes.Equal(raw, s.state.Load().raw) {
+		return nil
+	}
+	if err == nil && s.rejected != nil && bytes.Equal(raw, s.rejected) {
+		return *s.loadErr.Load()
+	}
+	s.rejected = nil
+	var servers map[string]ConfigServer
+	if err == nil {
+		err = s.check(raw)
+	}
+	if err == nil {
+		servers, err = parseConfig(raw)
+	}
+	if err != nil {
+		s.loadErr.Store(&err)
+		s.rejected = raw
+		if s.state.Load() != nil {
+			log.Printf("Error reloading config from %s, keeping the last good config: %v", s.path, err)
+		}
+		return err
+	}
+
+	first := s.state.Load() == nil
+	s.state.Store(&configState{servers: servers, raw: raw})
+	s.loadErr.Store(nil)
+	if first {
+		log.Printf("Config read successfully from %s with %d servers", s.path, len(servers))
+		return nil
+	}
+	log.Printf("Config reloaded from %s with %d servers", s.path, len(servers))
+
+	s.mu.Lock()
+	listeners := append([]func(map[string]ConfigServer){}, s.listeners...)
+	s.mu.Unlock()
+	for _, listener := range listeners {
+		listener(servers)
+	}
+	return nil
+}
+
+// check logs every problem ValidateConfig finds, and fails on errors unless Config.Lenient is set.
+func (s *ConfigStore) check(raw []byte) error {
+	problems := ValidateConfig(raw)
+	fatal := 0
+	for _, problem := range problems {
+		log.Println(problem.Format(s.path))
+		if problem.Fatal {
+			fatal++
+		}
+	}
+	if fatal > 0 && !Config.Lenient {
+		return fmt.Errorf("%s has %d errors", s.path, fatal)
+	}
+	return nil
+}
+
+// watch follows the directory rather than the file, so atomic replaces by rename and
+// Kubernetes ConfigMap updates, which swap a ..data symlink, are noticed too.
+func (s *ConfigStore) watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(s.path)); err != nil {
+		watcher.Close()
+		return err
+	}
+	s.watcher = watcher
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// Only the file itself and the ..data entries of a ConfigMap matter
+				base := filepath.Base(event.Name)
+				if event.Op == fsnotify.Chmod || (base != filepath.Base(s.path) && !strings.HasPrefix(base, "..")) {
+					continue
+				}
+				s.scheduleReload()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Println("Error watching config:", err)
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *ConfigStore) scheduleReload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.timer = time.AfterFunc(reloadDelay, func() { s.reload() })
+}
+
+// Close stops watching the file.
+func (s *ConfigStore) Close() error {
+	if s.watcher == nil {
+		return nil
+	}
+	return s.watcher.Close()
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigStoreMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rcon.yaml")
+	store := &ConfigStore{}
+	if err := store.Start(path); err != nil {
+		t.Fatalf("Start without rcon.yaml: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	servers, err := store.Snapshot()
+	if err != nil || len(servers) != 0 {
+		t.Fatalf("Snapshot = %v, %v, want no servers", servers, err)
+	}
+
+	// Creating the file later loads its servers
+	changed := make(chan map[string]ConfigServer, 4)
+	store.OnChange(func(servers map[string]ConfigServer) { changed <- servers })
+	if err := os.WriteFile(path, []byte("alpha:\n  address: 127.0.0.1:25575\n  password: secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if servers, _ := store.Snapshot(); servers["alpha"].Address != "127.0.0.1:25575" {
+		t.Fatalf("Snapshot after creating the file = %v", servers)
+	}
+	if servers := <-changed; len(servers) != 1 {
+		t.Errorf("listener got %v, want alpha", servers)
+	}
+
+	// A broken or deleted file keeps the last good config
+	for _, change := range []func() error{
+		func() error { return os.WriteFile(path, []byte("alpha:\n  adress: nowhere\n"), 0600) },
+		func() error { return os.Remove(path) },
+	} {
+		if err := change(); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.reload(); err == nil {
+			t.Error("reload succeeded, want an error")
+		}
+		if servers, err := store.Snapshot(); err != nil || len(servers) != 1 {
+			t.Errorf("Snapshot = %v, %v, want the last good config", servers, err)
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// ConfigProblem is a mistake in rcon.yaml found by ValidateConfig.
+type ConfigProblem struct {
+	Line    int    // 0 when the problem is about the whole file
+	Server  string // empty when the problem is not about one server
+	Message string
+	Fatal   bool // the config can't be used as written; otherwise it is a warning
+}
+
+// Format describes the problem the way compilers do, as path:line: level: message.
+func (p ConfigProblem) Format(path string) string {
+	level := "warning"
+	if p.Fatal {
+		level = "error"
+	}
+	location := path
+	if p.Line > 0 {
+		location = fmt.Sprintf("%s:%d", path, p.Line)
+	}
+	message := p.Message
+	if p.Server != "" {
+		message = fmt.Sprintf("server '%s': %s", p.Server, message)
+	}
+	return Redact(fmt.Sprintf("%s: %s: %s", location, level, message))
+}
+
+// HasFatalProblems reports whether any of the problems is an error.
+func HasFatalProblems(problems []ConfigProblem) bool {
+	for _, problem := range problems {
+		if problem.Fatal {
+			return true
+		}
+	}
+	return false
+}
+
+// Keys rcon.yaml may use besides the fields of ConfigServer and WebhookConfig
+var extraServerKeys = []string{
+	"log", // read by rcon-cli, which shares the file
+}
+
+var (
+	serverKeys  = yamlKeys(reflect.TypeOf(ConfigServer{}), extraServerKeys...)
+	webhookKeys = yamlKeys(reflect.TypeOf(WebhookConfig{}))
+)
+
+// yamlKeys lists the keys yaml decodes into the fields of t.
+func yamlKeys(t reflect.Type, extra ...string) []string {
+	keys := append([]string{}, extra...)
+	for i := 0; i < t.NumField(); i++ {
+		key := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if key == "" {
+			key = strings.ToLower(t.Field(i).Name)
+		}
+		if key != "-" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ValidateConfig checks the content of rcon.yaml more strictly than loading it does:
+// unknown keys, duplicate names, missing addresses and passwords, malformed addresses,
+// unsupported types and invalid durations are all reported with their line.
+func ValidateConfig(data []byte) []ConfigProblem {
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &root); err != nil {
+		return []ConfigProblem{problemFromError("", 0, err)}
+	}
+	if len(root.Content) == 0 {
+		return []ConfigProblem{{Message: "no servers are configured"}}
+	}
+	document := root.Content[0]
+	if document.Kind != yamlv3.MappingNode {
+		return []ConfigProblem{{Line: document.Line, Message: "the file must map server names to their settings", Fatal: true}}
+	}
+
+	var problems []ConfigProblem
+	seen := make(map[string]int)
+	for i := 0; i+1 < len(document.Content); i += 2 {
+		key, value := document.Content[i], document.Content[i+1]
+		name := key.Value
+		if first, ok := seen[name]; ok {
+			problems = append(problems, ConfigProblem{Line: key.Line, Server: name,
+				Message: fmt.Sprintf("duplicate server name, first defined on line %d", first), Fatal: true})
+			continue
+		}
+		seen[name] = key.Line
+		problems = append(problems, validateServerNode(name, key, value)...)
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+	return problems
+}
+
+// shortSecretLength is the length below which redacting a password hits unrelated text.
+const shortSecretLength = 4
+
+// validateServerNode checks the settings of one server.
+func validateServerNode(name string, key *yamlv3.Node, value *yamlv3.Node) []ConfigProblem {
+	var problems []ConfigProblem
+	undecoded := make(map[int]bool) // lines of settings with the wrong type
+	fatal := func(line int, format string, args ...interface{}) {
+		if undecoded[line] {
+			return
+		}
+		problems = append(problems, ConfigProblem{Line: line, Server: name, Message: fmt.Sprintf(format, args...), Fatal: true})
+	}
+	warn := func(line int, format string, args ...interface{}) {
+		problems = append(problems, ConfigProblem{Line: line, Server: name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if name == "" || strings.ContainsAny(name, "/?#") {
+		warn(key.Line, "the name can't be used in URLs such as /v1/servers/{name}")
+	}
+	if value.Kind != yamlv3.MappingNode {
+		fatal(key.Line, "settings must be a mapping of keys such as address and password")
+		return problems
+	}
+	problems = append(problems, checkKeys(name, value, serverKeys)...)
+
+	// With type errors the other settings are still decoded and checked, but a setting
+	// that failed to decode is reported once, not again for its missing value
+	var server ConfigServer
+	if err := value.Decode(&server); err != nil {
+		var typeErr *yamlv3.TypeError
+		if !errors.As(err, &typeErr) {
+			return append(problems, problemsFromDecode(name, key.Line, err)...)
+		}
+		for _, problem := range problemsFromDecode(name, key.Line, err) {
+			problems = append(problems, problem)
+			undecoded[problem.Line] = true
+		}
+	}
+	server.normalizeType()
+	line := func(name string) int { return keyLine(value, name, key.Line) }
+
+	resolved := true
+	if err := server.resolveSecrets(); err != nil {
+		var secretErr *secretError
+		if errors.As(err, &secretErr) {
+			fatal(line(secretErr.key), "%v", secretErr.err)
+		} else {
+			fatal(key.Line, "%v", err)
+		}
+		resolved = false
+	}
+
+	switch server.Type {
+	case "", BackendRcon, BackendRest:
+	default:
+		fatal(line("type"), "unsupported type %q, use %s or %s", server.Type, BackendRcon, BackendRest)
+	}
+
+	if strings.TrimSpace(server.Address) == "" {
+		fatal(line("address"), "address is empty")
+	} else if err := checkAddress(server.Type, server.Address); err != nil {
+		fatal(line("address"), "invalid address %q: %v", server.Address, err)
+	}
+	if resolved && server.Password == "" {
+		fatal(line("password"), "password is empty")
+	} else if resolved && len(server.Password) < shortSecretLength {
+		warn(line("password"), "password is shorter than %d characters, redacting it from logs also hides unrelated text", shortSecretLength)
+	}
+	if server.Username != "" && server.Type != BackendRest {
+		warn(line("username"), "username is only used by type %s", BackendRest)
+	}
+
+	for _, setting := range server.settingErrors() {
+		fatal(line(setting.key), "%v", setting.err)
+	}
+
+	if webhooks := mappingValue(value, "webhooks"); resolved && webhooks != nil && webhooks.Kind == yamlv3.SequenceNode {
+		for i, node := range webhooks.Content {
+			if i < len(server.Webhooks) {
+				problems = append(problems, validateWebhookNode(name, i, node, server.Webhooks[i])...)
+			}
+		}
+	}
+	return problems
+}
+
+// validateWebhookNode checks one entry of a server's webhooks.
+func validateWebhookNode(name string, index int, node *yamlv3.Node, webhook WebhookConfig) []ConfigProblem {
+	var problems []ConfigProblem
+	fatal := func(line int, format string, args ...interface{}) {
+		message := fmt.Sprintf("webhook %d: ", index+1) + fmt.Sprintf(format, args...)
+		problems = append(problems, ConfigProblem{Line: line, Server: name, Message: message, Fatal: true})
+	}
+	if node.Kind != yamlv3.MappingNode {
+		fatal(node.Line, "must be a mapping of keys such as url and format")
+		return problems
+	}
+	for _, problem := range checkKeys(name, node, webhookKeys) {
+		problem.Message = fmt.Sprintf("webhook %d: %s", index+1, problem.Message)
+		problems = append(problems, problem)
+	}
+	line := func(key string) int { return keyLine(node, key, node.Line) }
+
+	if webhook.URL == "" {
+		fatal(node.Line, "url is empty")
+	} else if parsed, err := url.Parse(webhook.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		fatal(line("url"), "invalid url %q, use an http or https URL", webhook.URL)
+	}
+	switch strings.ToLower(webhook.Format) {
+	case "", WebhookGeneric, WebhookDiscord, WebhookSlack:
+	default:
+		fatal(line("format"), "unsupported format %q, use %s, %s or %s", webhook.Format, WebhookGeneric, WebhookDiscord, WebhookSlack)
+	}
+	for _, event := range webhook.Events {
+		switch event {
+		case EventPlayerJoined, EventPlayerLeft, EventServerOnline, EventServerOffline:
+		default:
+			fatal(line("events"), "unknown event %q, use %s, %s, %s or %s", event,
+				EventPlayerJoined, EventPlayerLeft, EventServerOnline, EventServerOffline)
+		}
+	}
+	if webhook.Template != "" {
+		if _, err := template.New("webhook").Funcs(template.FuncMap{"json": templateJSON}).Parse(webhook.Template); err != nil {
+			fatal(line("template"), "invalid template: %v", err)
+		}
+	}
+	if webhook.Retries != nil && *webhook.Retries < 0 {
+		fatal(line("retries"), "retries must not be negative, got %d", *webhook.Retries)
+	}
+	return problems
+}
+
+// checkKeys reports keys of the mapping that aren't in known, and keys given twice.
+func checkKeys(name string, mapping *yamlv3.Node, known []string) []ConfigProblem {
+	var problems []ConfigProblem
+	seen := make(map[string]int)
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key := mapping.Content[i]
+		if first, ok := seen[key.Value]; ok {
+			problems = append(problems, ConfigProblem{Line: key.Line, Server: name,
+				Message: fmt.Sprintf("duplicate key '%s', first set on line %d", key.Value, first), Fatal: true})
+			continue
+		}
+		seen[key.Value] = key.Line
+
+		index := sort.SearchStrings(known, key.Value)
+		if index < len(known) && known[index] == key.Value {
+			continue
+		}
+		message := fmt.Sprintf("unknown key '%s'", key.Value)
+		if suggestion := closestKey(key.Value, known); suggestion != "" {
+			message += fmt.Sprintf(", did you mean '%s'?", suggestion)
+		}
+		problems = append(problems, ConfigProblem{Line: key.Line, Server: name, Message: message, Fatal: true})
+	}
+	return problems
+}
+
+// checkAddress accepts host:port, and for REST servers also a full http(s) URL.
+func checkAddress(backendType string, address string) error {
+	if backendType == BackendRest && strings.Contains(address, "://") {
+		parsed, err := url.Parse(address)
+		if err != nil {
+			return err
+		}
+		if parsed.Scheme != "http" && parsed.Scheme != "https" {
+			return fmt.Errorf("use an http or https URL")
+		}
+		if parsed.Host == "" {
+			return fmt.Errorf("the URL has no host")
+		}
+		return nil
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("use host:port, for example 127.0.0.1:25575")
+	}
+	if host == "" {
+		return fmt.Errorf("the host is empty")
+	}
+	number, err := strconv.Atoi(port)
+	if err != nil || number < 1 || number > 65535 {
+		return fmt.Errorf("the port must be a number from 1 to 65535")
+	}
+	return nil
+}
+
+// mappingValue returns the value of key in the mapping, or nil.
+func mappingValue(mapping *yamlv3.Node, key string) *yamlv3.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// keyLine is the line of key in the mapping, or fallback if key is missing.
+func keyLine(mapping *yamlv3.Node, key string, fallback int) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i].Line
+		}
+	}
+	return fallback
+}
+
+var errorLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): `)
+
+// problemFromError turns a yaml error into a problem, taking the line from its message.
+func problemFromError(name string, line int, err error) ConfigProblem {
+	message := err.Error()
+	if match := errorLine.FindStringSubmatch(message); match != nil {
+		line, _ = strconv.Atoi(match[1])
+		message = message[len(match[0]):]
+	}
+	return ConfigProblem{Line: line, Server: name, Message: message, Fatal: true}
+}
+
+// problemsFromDecode reports each value of the wrong type, such as retries: two.
+func problemsFromDecode(name string, line int, err error) []ConfigProblem {
+	var typeErr *yamlv3.TypeError
+	if !errors.As(err, &typeErr) {
+		return []ConfigProblem{problemFromError(name, line, err)}
+	}
+	problems := make([]ConfigProblem, 0, len(typeErr.Errors))
+	for _, text := range typeErr.Errors {
+		problems = append(problems, problemFromError(name, line, errors.New(text)))
+	}
+	return problems
+}
+
+// closestKey suggests the known key a typo was probably meant to be.
+func closestKey(key string, known []string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range known {
+		if distance := editDistance(key, candidate); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"palworld-query-api/internal/rcontest"
+)
+
+func TestServerTypeIgnoresCase(t *testing.T) {
+	tests := []struct {
+		value   string
+		backend string // empty when the type is not supported
+	}{
+		{"", BackendRcon},
+		{"rcon", BackendRcon},
+		{"RCON", BackendRcon},
+		{"Rest", BackendRest},
+		{"telnet", ""},
+	}
+	for _, test := range tests {
+		data := []byte("alpha:\n  address: 127.0.0.1:25575\n  password: secret\n  type: \"" + test.value + "\"\n")
+
+		problems := ValidateConfig(data)
+		if fatal := HasFatalProblems(problems); fatal != (test.backend == "") {
+			t.Errorf("type %q: validate-config reports %v", test.value, problems)
+		}
+
+		servers, err := parseConfig(data)
+		if err != nil {
+			t.Fatalf("type %q: %v", test.value, err)
+		}
+		backend, err := NewBackend(servers["alpha"])
+		switch backend.(type) {
+		case *rconBackend:
+			if test.backend != BackendRcon {
+				t.Errorf("type %q selects the RCON backend", test.value)
+			}
+		case *restBackend:
+			if test.backend != BackendRest {
+				t.Errorf("type %q selects the REST backend", test.value)
+			}
+		default:
+			if test.backend != "" {
+				t.Errorf("type %q: NewBackend: %v", test.value, err)
+			}
+		}
+	}
+}
+
+func TestPoolRetainIgnoresTypeCase(t *testing.T) {
+	server := startServer(t, rcontest.Config{Password: "secret"})
+	pool := newTestPool()
+	defer pool.Close()
+
+	servers, err := parseConfig([]byte("alpha:\n  address: " + server.Addr() + "\n  password: secret\n  type: RCON\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Execute(servers["alpha"], Rcon.Command.Info); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	pool.Retain(servers)
+	if got := len(pool.conns); got != 1 {
+		t.Errorf("Retain left %d connections, want the server's connection", got)
+	}
+}
+
+func TestValidateConfigKeepsCheckingAfterTypeErrors(t *testing.T) {
+	data := []byte(`bad:
+  address: "x"
+  password: secret
+  retries: two
+  timeout: 5
+  breaker_threshold: -1
+list:
+  address: [127.0.0.1, 25575]
+  password: secret
+  read_timeout: 0s
+`)
+	want := map[int]string{
+		2:  `invalid address "x"`,
+		4:  "cannot unmarshal",
+		5:  `invalid timeout "5"`,
+		6:  "breaker_threshold must not be negative",
+		8:  "cannot unmarshal",
+		10: "read_timeout must be positive",
+	}
+	problems := ValidateConfig(data)
+	if len(problems) != len(want) {
+		t.Errorf("got %d problems, want %d: %v", len(problems), len(want), problems)
+	}
+	for _, problem := range problems {
+		if !problem.Fatal || !strings.Contains(problem.Message, want[problem.Line]) || want[problem.Line] == "" {
+			t.Errorf("unexpected problem on line %d: %s", problem.Line, problem.Message)
+		}
+	}
+}
+
+func TestLoaderAndValidatorAgree(t *testing.T) {
+	tests := []string{
+		"timeout: 1m",
+		"timeout: soon",
+		"read_timeout: -1s",
+		"retry_backoff: 0s",
+		"breaker_cooldown: 30",
+		"retries: -1",
+		"breaker_threshold: -2",
+		"breaker_threshold: 0",
+	}
+	for _, setting := range tests {
+		data := []byte("alpha:\n  address: 127.0.0.1:25575\n  password: secret\n  " + setting + "\n")
+		_, loadErr := parseConfig(data)
+		problems := ValidateConfig(data)
+		if (loadErr != nil) != HasFatalProblems(problems) {
+			t.Errorf("%s: loading says %v, validate-config says %v", setting, loadErr, problems)
+			continue
+		}
+		if loadErr != nil && !strings.HasSuffix(loadErr.Error(), problems[0].Message) {
+			t.Errorf("%s: loading says %q, validate-config says %q", setting, loadErr, problems[0].Message)
+		}
+	}
+}
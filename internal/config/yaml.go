@@ -1,93 +1,200 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"log"
+	"strings"
+	"time"
 	"gopkg.in/yaml.v2"
-	"github.com/fsnotify/fsnotify"
 )
+// ErrServerNotFound is returned for names that are not in rcon.yaml.
+var ErrServerNotFound = errors.New("server not found")
+
 type ConfigServer struct {
+	Name     string `json:"-" yaml:"-"` // key of the server in rcon.yaml
 	Address  string `json:"address"`
-	Password string `json:"password"`
-	Type     string `json:"type"`
-	Timeout  string `json:"timeout"`
+	Username string `json:"username"` // basic auth user for the REST backend, "admin" by default
+	Password string `json:"-"` // never marshalled, so it can't end up in API output
+	PasswordFile string `json:"password_file" yaml:"password_file"` // file holding the password, such as a mounted secret
+	PasswordEnv  string `json:"password_env" yaml:"password_env"`   // environment variable holding the password
+	Type     string `json:"type"` // rcon (default) or rest
+	Timeout  string `json:"timeout"` // connect timeout, and read timeout unless read_timeout is set
+	ReadTimeout  string `json:"read_timeout" yaml:"read_timeout"` // deadline for each command's answer
+	Retries      int    `json:"retries" yaml:"retries"` // extra attempts of failed status queries
+	RetryBackoff string `json:"retry_backoff" yaml:"retry_backoff"` // wait before the first retry, doubled after each
+	BreakerThreshold *int  `json:"breaker_threshold" yaml:"breaker_threshold"` // Config.BreakerThreshold by default, 0 disables
+	BreakerCooldown  string `json:"breaker_cooldown" yaml:"breaker_cooldown"`  // Config.BreakerCooldown by default
+	Webhooks []WebhookConfig `json:"webhooks"`
+	DisplayName   string   `json:"display_name" yaml:"display_name"`     // name for dashboards, instead of the key
+	Tags          []string `json:"tags" yaml:"tags"`                     // labels such as pvp or eu, for ?tag= filters
+	Group         string   `json:"group" yaml:"group"`                   // servers of a group are summed up by /v1/groups/{group}
+	PublicAddress string   `json:"public_address" yaml:"public_address"` // address players connect to, shown instead of address
+	Description   string   `json:"description" yaml:"description"`
+	Icon          string   `json:"icon" yaml:"icon"` // image URL
 }
 
-// Function to read the YAML config file and return the content
-func GetConfig() (map[string]ConfigServer, error) {
-	filePath := Config.CliConfig
-
-	// Log the file path
-	log.Printf("Reading config from file: %s", filePath)
+// ServerMeta is the metadata of a server from rcon.yaml, served with its status.
+type ServerMeta struct {
+	DisplayName   string   `json:"displayName,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	Group         string   `json:"group,omitempty"`
+	PublicAddress string   `json:"publicAddress,omitempty"`
+	Description   string   `json:"description,omitempty"`
+	Icon          string   `json:"icon,omitempty"`
+}
 
-	// Check if the file exists
-	_, err := os.Stat(filePath)
-	if os.IsNotExist(err) {
-		return nil, err
+// Meta returns the metadata of the server, or nil if rcon.yaml gives none.
+func (s ConfigServer) Meta() *ServerMeta {
+	meta := ServerMeta{
+		DisplayName:   s.DisplayName,
+		Tags:          s.Tags,
+		Group:         s.Group,
+		PublicAddress: s.PublicAddress,
+		Description:   s.Description,
+		Icon:          s.Icon,
+	}
+	if meta.DisplayName == "" && len(meta.Tags) == 0 && meta.Group == "" && meta.PublicAddress == "" && meta.Description == "" && meta.Icon == "" {
+		return nil
 	}
+	return &meta
+}
 
-	// Read YAML file
-	yamlFile, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		return nil, err
+// HasTag reports whether the server is tagged with tag, ignoring case.
+func (s ConfigServer) HasTag(tag string) bool {
+	for _, t := range s.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
 	}
+	return false
+}
 
-	// Unmarshal YAML into map
-	var data map[string]ConfigServer
-	err = yaml.Unmarshal(yamlFile, &data)
-	if err != nil {
-		return nil, err
+// InGroup reports whether the server belongs to group, ignoring case.
+func (s ConfigServer) InGroup(group string) bool {
+	return s.Group != "" && strings.EqualFold(s.Group, group)
+}
+
+// normalizeType lower-cases the server's type, so "RCON" or "Rest" select a backend
+// too. Everything that reads the type can then compare it with BackendRcon and BackendRest.
+func (s *ConfigServer) normalizeType() {
+	s.Type = strings.ToLower(s.Type)
+}
+
+// validate rejects settings that can't be used, so mistakes show up when the config is loaded.
+func (s ConfigServer) validate() error {
+	if errs := s.settingErrors(); len(errs) > 0 {
+		return fmt.Errorf("server '%s': %v", s.Name, errs[0].err)
 	}
+	return nil
+}
 
-	// Create a new watcher to monitor changes to the file
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		return nil, err
+// settingError is an unusable setting of a server.
+type settingError struct {
+	key string // setting in rcon.yaml, to report its line
+	err error
+}
+
+// settingErrors checks the durations, retries and breaker threshold of the server.
+// Loading stops at the first error, validate-config reports them all.
+func (s ConfigServer) settingErrors() []settingError {
+	var errs []settingError
+	durations := []struct {
+		key   string
+		value string
+	}{
+		{"timeout", s.Timeout},
+		{"read_timeout", s.ReadTimeout},
+		{"retry_backoff", s.RetryBackoff},
+		{"breaker_cooldown", s.BreakerCooldown},
 	}
-	defer watcher.Close()
-
-	// Watch for changes to the file
-	go func() {
-		for {
-			select {
-			case event, ok := <-watcher.Events:
-				if !ok {
-					return
-				}
-				if event.Op&fsnotify.Write == fsnotify.Write {
-					log.Println("Config file modified. Reloading config...")
-					// Reload config from file
-					reloadData, err := GetConfig()
-					if err != nil {
-						log.Println("Error reloading config:", err)
-						continue
-					}
-					log.Println("Config reloaded successfully")
-					// Update the existing data with the reloaded data
-					data = reloadData
-				}
-			case err, ok := <-watcher.Errors:
-				if !ok {
-					return
-				}
-				log.Println("Error watching file:", err)
-			}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(d.value)
+		if err != nil {
+			errs = append(errs, settingError{d.key, fmt.Errorf("invalid %s %q, use a duration like \"10s\" or \"1m30s\"", d.key, d.value)})
+		} else if duration <= 0 {
+			errs = append(errs, settingError{d.key, fmt.Errorf("%s must be positive, got %q", d.key, d.value)})
 		}
-	}()
+	}
+	if s.Retries < 0 {
+		errs = append(errs, settingError{"retries", fmt.Errorf("retries must not be negative, got %d", s.Retries)})
+	}
+	if s.BreakerThreshold != nil && *s.BreakerThreshold < 0 {
+		errs = append(errs, settingError{"breaker_threshold", fmt.Errorf("breaker_threshold must not be negative, got %d", *s.BreakerThreshold)})
+	}
+	return errs
+}
+
+// DialTimeoutDuration is how long to wait for a connection to the server.
+func (s ConfigServer) DialTimeoutDuration() time.Duration {
+	return parseDurationOr(s.Timeout, PoolConfig.DialTimeout)
+}
+
+// ReadTimeoutDuration is how long to wait for the answer to each command.
+func (s ConfigServer) ReadTimeoutDuration() time.Duration {
+	return parseDurationOr(s.ReadTimeout, parseDurationOr(s.Timeout, PoolConfig.ReadTimeout))
+}
 
-	// Add the file to the watcher
-	err = watcher.Add(filePath)
+// RetryBackoffDuration is how long to wait before retrying a failed status query.
+func (s ConfigServer) RetryBackoffDuration() time.Duration {
+	return parseDurationOr(s.RetryBackoff, PoolConfig.RetryBackoff)
+}
+
+// BreakerThresholdValue is how many failures in a row open the server's circuit breaker.
+func (s ConfigServer) BreakerThresholdValue() int {
+	if s.BreakerThreshold != nil {
+		return *s.BreakerThreshold
+	}
+	return Config.BreakerThreshold
+}
+
+// BreakerCooldownDuration is how long an open circuit breaker waits before probing the server.
+func (s ConfigServer) BreakerCooldownDuration() time.Duration {
+	return parseDurationOr(s.BreakerCooldown, Config.BreakerCooldown)
+}
+
+// parseDurationOr parses value, falling back to fallback when it is empty or invalid.
+func parseDurationOr(value string, fallback time.Duration) time.Duration {
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return fallback
+	}
+	return duration
+}
+
+// GetConfig returns the servers of rcon.yaml as last loaded by ServerConfigs.
+// The map is shared and must not be modified.
+func GetConfig() (map[string]ConfigServer, error) {
+	return ServerConfigs.Snapshot()
+}
+
+// parseConfig unmarshals and validates the content of rcon.yaml.
+func parseConfig(yamlFile []byte) (map[string]ConfigServer, error) {
+	// Unmarshal YAML into map
+	var data map[string]ConfigServer
+	err := yaml.Unmarshal(yamlFile, &data)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Println("Config read successfully from file.")
+	// Let every server know its own name, and fill in its credentials
+	for name, server := range data {
+		server.Name = name
+		server.normalizeType()
+		if err := server.resolveSecrets(); err != nil {
+			return nil, fmt.Errorf("server '%s': %v", name, err)
+		}
+		if err := server.validate(); err != nil {
+			return nil, err
+		}
+		data[name] = server
+	}
 	return data, nil
 }
 
-// reads the YAML config file and returns the configuration for a specific server
+// returns the configuration for a specific server from rcon.yaml
 func GetServerConfig(serverName string) (ConfigServer, error) {
 	data, err := GetConfig()
 	if err != nil {
@@ -97,7 +204,7 @@ func GetServerConfig(serverName string) (ConfigServer, error) {
 	// Check if the server name exists
 	config, ok := data[serverName]
 	if !ok {
-		return ConfigServer{}, fmt.Errorf("server '%s' not found", serverName)
+		return ConfigServer{}, fmt.Errorf("%w: '%s'", ErrServerNotFound, serverName)
 	}
 
 	return config, nil
@@ -0,0 +1,414 @@
+// Package rcontest runs a fake Palworld dedicated server that speaks the Source RCON
+// protocol, for exercising the RCON backend without a real game server.
+package rcontest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source RCON packet types
+const (
+	packetResponseValue = 0
+	packetExecCommand   = 2
+	packetAuthResponse  = 2
+	packetAuth          = 3
+)
+
+// Packet size limits of the Source RCON protocol
+const (
+	minPacketSize = 10
+	maxPacketSize = 4096 + minPacketSize
+)
+
+// Player is a player the fake server reports as online.
+type Player struct {
+	Name      string `json:"name"`
+	PlayerUID string `json:"playerUid"`
+	SteamID   string `json:"steamId"`
+}
+
+// Faults make the fake server misbehave the way real servers sometimes do.
+type Faults struct {
+	AuthFailure bool // refuse every password
+	Truncate    bool // send only half of each command answer, then close the connection
+	NullBytes   bool // pad answers with null bytes, as some server builds do
+	Hang        bool // accept commands but never answer them
+}
+
+// Config describes the fake server.
+type Config struct {
+	Address  string        // listen address, 127.0.0.1:0 by default
+	Password string        // admin password clients must send
+	Name     string        // server name reported by Info
+	Version  string        // version reported by Info
+	Players  []Player      // players online at start
+	Latency  time.Duration // delay before every answer
+	Faults   Faults
+	Script   []Step // player joins and leaves played back after start
+}
+
+// Step is one scripted change of the player list, At after the server started.
+// Steps are played in order.
+type Step struct {
+	At    time.Duration
+	Join  *Player
+	Leave string // Steam ID of the player who leaves
+}
+
+// Server is a running fake Palworld server.
+type Server struct {
+	mu       sync.Mutex
+	config   Config
+	players  []Player
+	banned   map[string]bool
+	commands []string
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewServer starts listening and serving right away. Close stops it.
+func NewServer(config Config) (*Server, error) {
+	if config.Address == "" {
+		config.Address = "127.0.0.1:0"
+	}
+	if config.Name == "" {
+		config.Name = "Fake Palworld Server"
+	}
+	if config.Version == "" {
+		config.Version = "v0.1.5.1"
+	}
+
+	listener, err := net.Listen("tcp", config.Address)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		config:   config,
+		players:  append([]Player(nil), config.Players...),
+		banned:   make(map[string]bool),
+		listener: listener,
+		conns:    make(map[net.Conn]struct{}),
+		closed:   make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+	if len(config.Script) > 0 {
+		s.wg.Add(1)
+		go s.play(config.Script)
+	}
+	return s, nil
+}
+
+// Addr is the host:port the server listens on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the server and closes every open connection.
+func (s *Server) Close() error {
+	select {
+	case <-s.closed:
+		return nil
+	default:
+	}
+	close(s.closed)
+	err := s.listener.Close()
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// Players returns the players currently online.
+func (s *Server) Players() []Player {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Player(nil), s.players...)
+}
+
+// SetPlayers replaces the players online.
+func (s *Server) SetPlayers(players []Player) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.players = append([]Player(nil), players...)
+}
+
+// Join adds a player, replacing one with the same Steam ID.
+func (s *Server) Join(player Player) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.players = append(s.without(player.SteamID), player)
+}
+
+// Leave removes the player with the given Steam ID and reports whether they were online.
+func (s *Server) Leave(steamID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	remaining := s.without(steamID)
+	left := len(remaining) != len(s.players)
+	s.players = remaining
+	return left
+}
+
+// SetFaults changes the injected faults. Open connections are affected from their next packet.
+func (s *Server) SetFaults(faults Faults) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.Faults = faults
+}
+
+// SetLatency changes the delay before every answer.
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.Latency = latency
+}
+
+// Commands returns every command received so far, in order.
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// Banned reports whether the player with the given Steam ID was banned.
+func (s *Server) Banned(steamID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.banned[strings.TrimPrefix(steamID, "steam_")]
+}
+
+func (s *Server) without(steamID string) []Player {
+	steamID = strings.TrimPrefix(steamID, "steam_")
+	players := make([]Player, 0, len(s.players))
+	for _, player := range s.players {
+		if player.SteamID != steamID {
+			players = append(players, player)
+		}
+	}
+	return players
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.closed:
+			default:
+				log.Println("rcontest: accept:", err)
+			}
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+// play applies the scripted joins and leaves at their time.
+func (s *Server) play(script []Step) {
+	defer s.wg.Done()
+	start := time.Now()
+	for _, step := range script {
+		select {
+		case <-time.After(time.Until(start.Add(step.At))):
+		case <-s.closed:
+			return
+		}
+		if step.Join != nil {
+			s.Join(*step.Join)
+		}
+		if step.Leave != "" {
+			s.Leave(step.Leave)
+		}
+	}
+}
+
+// handle answers the packets of one connection until it closes.
+func (s *Server) handle(conn net.Conn) {
+	authenticated := false
+	for {
+		id, packetType, body, err := readPacket(conn)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				log.Println("rcontest: read:", err)
+			}
+			return
+		}
+
+		s.mu.Lock()
+		config := s.config
+		s.mu.Unlock()
+		if config.Latency > 0 {
+			select {
+			case <-time.After(config.Latency):
+			case <-s.closed:
+				return
+			}
+		}
+
+		switch packetType {
+		case packetAuth:
+			authenticated = !config.Faults.AuthFailure && body == config.Password
+			responseID := id
+			if !authenticated {
+				responseID = -1
+			}
+			if err := writePacket(conn, responseID, packetAuthResponse, ""); err != nil {
+				return
+			}
+		case packetExecCommand:
+			if !authenticated {
+				// Real servers drop unauthenticated clients
+				return
+			}
+			answer := s.execute(body)
+			if config.Faults.Hang {
+				continue
+			}
+			if config.Faults.NullBytes {
+				answer += "\x00\x00\x00"
+			}
+			if config.Faults.Truncate {
+				writeTruncated(conn, id, answer)
+				return
+			}
+			if err := writePacket(conn, id, packetResponseValue, answer); err != nil {
+				return
+			}
+		default:
+			log.Printf("rcontest: unexpected packet type %d", packetType)
+			return
+		}
+	}
+}
+
+// execute runs a command and returns the answer a Palworld server would give.
+func (s *Server) execute(command string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands = append(s.commands, command)
+
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return "Unknown command\n"
+	}
+	args := fields[1:]
+	switch strings.ToLower(fields[0]) {
+	case "info":
+		return fmt.Sprintf("Welcome to Pal Server[%s] %s\n", s.config.Version, s.config.Name)
+	case "showplayers":
+		var answer strings.Builder
+		answer.WriteString("name,playeruid,steamid\n")
+		for _, player := range s.players {
+			fmt.Fprintf(&answer, "%s,%s,%s\n", player.Name, player.PlayerUID, player.SteamID)
+		}
+		return answer.String()
+	case "broadcast":
+		return fmt.Sprintf("Broadcasted: %s\n", strings.Join(args, " "))
+	case "kickplayer":
+		if len(args) == 0 {
+			return "Failed to kick player: missing Steam ID\n"
+		}
+		remaining := s.without(args[0])
+		if len(remaining) == len(s.players) {
+			return fmt.Sprintf("Failed to kick player: %s\n", args[0])
+		}
+		s.players = remaining
+		return fmt.Sprintf("Kicked: %s\n", args[0])
+	case "banplayer":
+		if len(args) == 0 {
+			return "Failed to ban player: missing Steam ID\n"
+		}
+		s.players = s.without(args[0])
+		s.banned[strings.TrimPrefix(args[0], "steam_")] = true
+		return fmt.Sprintf("Baned: %s\n", args[0])
+	case "save":
+		return "Complete Save\n"
+	case "shutdown":
+		seconds := 1
+		if len(args) > 0 {
+			if parsed, err := strconv.Atoi(args[0]); err == nil {
+				seconds = parsed
+			}
+		}
+		return fmt.Sprintf("The server will shut down in %d seconds. Please prepare to exit the game.\n", seconds)
+	case "doexit":
+		return "Shutdown...\n"
+	default:
+		return fmt.Sprintf("Unknown command: %s\n", fields[0])
+	}
+}
+
+// readPacket reads one packet: size, id and type as little endian int32, then the
+// body followed by two null bytes.
+func readPacket(r io.Reader) (int32, int32, string, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, 0, "", err
+	}
+	size := int32(binary.LittleEndian.Uint32(header[0:4]))
+	id := int32(binary.LittleEndian.Uint32(header[4:8]))
+	packetType := int32(binary.LittleEndian.Uint32(header[8:12]))
+	if size < minPacketSize || size > maxPacketSize {
+		return 0, 0, "", fmt.Errorf("invalid packet size %d", size)
+	}
+
+	body := make([]byte, size-8)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, 0, "", err
+	}
+	return id, packetType, string(bytes.TrimRight(body, "\x00")), nil
+}
+
+func encodePacket(id int32, packetType int32, body string) []byte {
+	var packet bytes.Buffer
+	binary.Write(&packet, binary.LittleEndian, int32(len(body)+minPacketSize))
+	binary.Write(&packet, binary.LittleEndian, id)
+	binary.Write(&packet, binary.LittleEndian, packetType)
+	packet.WriteString(body)
+	packet.Write([]byte{0, 0})
+	return packet.Bytes()
+}
+
+func writePacket(w io.Writer, id int32, packetType int32, body string) error {
+	_, err := w.Write(encodePacket(id, packetType, body))
+	return err
+}
+
+// writeTruncated sends the first half of the answer packet, as a server that crashes mid-write would.
+func writeTruncated(w io.Writer, id int32, body string) {
+	packet := encodePacket(id, packetResponseValue, body)
+	w.Write(packet[:len(packet)/2])
+}
@@ -0,0 +1,109 @@
+package rcontest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorcon/rcon"
+)
+
+func startServer(t *testing.T, config Config) *Server {
+	t.Helper()
+	server, err := NewServer(config)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+func dial(t *testing.T, server *Server, password string) *rcon.Conn {
+	t.Helper()
+	conn, err := rcon.Dial(server.Addr(), password, rcon.SetDeadline(time.Second))
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestCommands(t *testing.T) {
+	server := startServer(t, Config{
+		Password: "secret",
+		Players:  []Player{{Name: "Alice", PlayerUID: "1", SteamID: "111"}, {Name: "Bob", PlayerUID: "2", SteamID: "222"}},
+	})
+	conn := dial(t, server, "secret")
+
+	tests := []struct {
+		command string
+		answer  string
+	}{
+		{"Info", "Welcome to Pal Server[v0.1.5.1] Fake Palworld Server\n"},
+		{"ShowPlayers", "name,playeruid,steamid\nAlice,1,111\nBob,2,222\n"},
+		{"Broadcast Restart_soon", "Broadcasted: Restart_soon\n"},
+		{"KickPlayer 111", "Kicked: 111\n"},
+		{"KickPlayer 111", "Failed to kick player: 111\n"},
+		{"BanPlayer steam_222", "Baned: steam_222\n"},
+		{"ShowPlayers", "name,playeruid,steamid\n"},
+		{"Save", "Complete Save\n"},
+		{"Shutdown 30 bye", "The server will shut down in 30 seconds. Please prepare to exit the game.\n"},
+		{"Dance", "Unknown command: Dance\n"},
+	}
+	for _, test := range tests {
+		answer, err := conn.Execute(test.command)
+		if err != nil {
+			t.Fatalf("Execute(%q): %v", test.command, err)
+		}
+		if answer != test.answer {
+			t.Errorf("Execute(%q) = %q, want %q", test.command, answer, test.answer)
+		}
+	}
+	if !server.Banned("222") {
+		t.Error("player 222 is not banned")
+	}
+	if got := len(server.Commands()); got != len(tests) {
+		t.Errorf("server recorded %d commands, want %d", got, len(tests))
+	}
+}
+
+func TestAuthFailure(t *testing.T) {
+	server := startServer(t, Config{Password: "secret"})
+	if _, err := rcon.Dial(server.Addr(), "wrong"); !errors.Is(err, rcon.ErrAuthFailed) {
+		t.Errorf("Dial with a wrong password = %v, want %v", err, rcon.ErrAuthFailed)
+	}
+
+	server.SetFaults(Faults{AuthFailure: true})
+	if _, err := rcon.Dial(server.Addr(), "secret"); !errors.Is(err, rcon.ErrAuthFailed) {
+		t.Errorf("Dial with AuthFailure = %v, want %v", err, rcon.ErrAuthFailed)
+	}
+}
+
+func TestScript(t *testing.T) {
+	alice := Player{Name: "Alice", PlayerUID: "1", SteamID: "111"}
+	server := startServer(t, Config{
+		Password: "secret",
+		Script: []Step{
+			{At: 10 * time.Millisecond, Join: &alice},
+			{At: 200 * time.Millisecond, Leave: "111"},
+		},
+	})
+
+	waitFor := func(online int) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for len(server.Players()) != online {
+			if time.Now().After(deadline) {
+				t.Fatalf("%d players online, want %d", len(server.Players()), online)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	waitFor(1)
+	answer, err := dial(t, server, "secret").Execute("ShowPlayers")
+	if err != nil || !strings.Contains(answer, "Alice,1,111") {
+		t.Errorf("ShowPlayers after the join = %q, %v", answer, err)
+	}
+	waitFor(0)
+}
@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"palworld-query-api/internal/config"
+)
+
+type adminRequest struct {
+	Message string `json:"message"`
+	SteamID string `json:"steamId"`
+	Seconds int    `json:"seconds"`
+}
+
+// adminCommand runs an admin command against the named server and returns its result.
+type adminCommand func(configServer config.ConfigServer, req adminRequest) (*config.CommandResult, error)
+
+func BroadcastHandler(w http.ResponseWriter, r *http.Request) {
+	handleAdminCommand(w, r, func(configServer config.ConfigServer, req adminRequest) (*config.CommandResult, error) {
+		return config.Broadcast(configServer, req.Message)
+	})
+}
+
+func KickHandler(w http.ResponseWriter, r *http.Request) {
+	handleAdminCommand(w, r, func(configServer config.ConfigServer, req adminRequest) (*config.CommandResult, error) {
+		return config.KickPlayer(configServer, req.SteamID)
+	})
+}
+
+func BanHandler(w http.ResponseWriter, r *http.Request) {
+	handleAdminCommand(w, r, func(configServer config.ConfigServer, req adminRequest) (*config.CommandResult, error) {
+		return config.BanPlayer(configServer, req.SteamID)
+	})
+}
+
+func SaveHandler(w http.ResponseWriter, r *http.Request) {
+	handleAdminCommand(w, r, func(configServer config.ConfigServer, req adminRequest) (*config.CommandResult, error) {
+		return config.Save(configServer)
+	})
+}
+
+func ShutdownHandler(w http.ResponseWriter, r *http.Request) {
+	handleAdminCommand(w, r, func(configServer config.ConfigServer, req adminRequest) (*config.CommandResult, error) {
+		return config.Shutdown(configServer, req.Seconds, req.Message)
+	})
+}
+
+func ExitHandler(w http.ResponseWriter, r *http.Request) {
+	handleAdminCommand(w, r, func(configServer config.ConfigServer, req adminRequest) (*config.CommandResult, error) {
+		return config.DoExit(configServer)
+	})
+}
+
+func handleAdminCommand(w http.ResponseWriter, r *http.Request, command adminCommand) {
+	serverName := r.PathValue("name")
+	configServer, err := config.GetServerConfig(serverName)
+	if err != nil {
+		writeServerLookupError(w, serverName, err)
+		return
+	}
+
+	var req adminRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid JSON body")
+			return
+		}
+	}
+
+	result, err := command(configServer, req)
+	if err != nil {
+		if errors.Is(err, config.ErrInvalidArgument) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("Error running admin command on %s: %v", serverName, err)
+		writeServerError(w, serverName, err)
+		return
+	}
+
+	log.Printf("Ran %s on %s", result.Command, serverName)
+	writeJSON(w, http.StatusOK, result)
+}
+
+// writeJSON encodes v as the JSON response body with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
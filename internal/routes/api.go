@@ -1,12 +1,10 @@
 package routes
 
 import (
-    "encoding/json"
     "fmt"
     "html/template"
     "log"
     "net/http"
-    "net/url"
     "palworld-query-api/internal/config"
     "reflect"
     "strings"
@@ -51,7 +49,7 @@ func ApiHandler(w http.ResponseWriter, r *http.Request) {
     // Check if the "name" query parameter is provided
     nameQuery := queryParams.Get("name")
     if nameQuery == "" {
-        http.Error(w, "Name query parameter is required", http.StatusBadRequest)
+        writeError(w, http.StatusBadRequest, "Name query parameter is required")
         return
     }
 
@@ -60,122 +58,84 @@ func ApiHandler(w http.ResponseWriter, r *http.Request) {
         queryParams.Set("q", nameQuery)
     }
 
-    // Construct the initial search URL
-    searchURL := config.ApiConfig.Base + config.ApiConfig.Search + "?q=" + url.QueryEscape(queryParams.Get("q"))
-
-    var allServers []Server
-
-    // Pagination loop
-    for {
-        // Send a request to the search endpoint
-        response, err := http.Get(searchURL)
-        if err != nil {
-            log.Printf("Error searching for server: %s", err)
-            http.Error(w, fmt.Sprintf("Error searching for server: %s", err), http.StatusInternalServerError)
-            return
-        }
-        defer response.Body.Close()
-
-        // Decode the response JSON
-        var serverListResponse ServerListResponse
-        err = json.NewDecoder(response.Body).Decode(&serverListResponse)
-        if err != nil {
-            log.Printf("Error decoding search response: %s", err)
-            http.Error(w, fmt.Sprintf("Error decoding search response: %s", err), http.StatusInternalServerError)
+    // Parse the filters first, so mistakes don't cost an upstream search
+    filters, err := parseFilters(queryParams)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    for i := range filters {
+        if err := filters[i].resolveAddress(); err != nil {
+            writeError(w, http.StatusBadRequest, err.Error())
             return
         }
+    }
+    options, err := parseResultOptions(queryParams)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    // Only the legacy shapes answer 404 for no servers, the envelope is just empty
+    legacy := options.single || acceptsHTML(r)
 
-        // If no servers found, return empty response
-        if len(serverListResponse.ServerList) == 0 {
-            log.Println("No servers found.")
-            http.Error(w, "No servers found.", http.StatusNotFound)
-            return
-        }
+    // Search upstream, or reuse a recent search for the same query
+    allServers, cached, err := upstream.search(queryParams.Get("q"))
+    if err != nil {
+        log.Printf("Error searching for server: %s", err)
+        writeServerError(w, "", fmt.Errorf("Error searching for server: %w", err))
+        return
+    }
+    if cached {
+        w.Header().Set("X-Cache", "HIT")
+    } else {
+        w.Header().Set("X-Cache", "MISS")
+    }
 
-        // Append servers to the result
-        allServers = append(allServers, serverListResponse.ServerList...)
+    // If no servers found, return empty response
+    if len(allServers) == 0 && legacy {
+        log.Println("No servers found.")
+        writeError(w, http.StatusNotFound, "No servers found.")
+        return
+    }
 
-        // Check if there are more pages
-        if !serverListResponse.IsNextPage {
-            break
-        }
+    // Filter servers based on query parameters other than "q", then sort and cut out the page
+    filteredServers := filterServers(allServers, filters)
+    log.Printf("Number of servers before filtering: %d, after filtering: %d", len(allServers), len(filteredServers))
+    sortServers(filteredServers, options.sort)
+    page := pageOf(filteredServers, options)
 
-        // Update the search URL for the next page
-        searchURL = config.ApiConfig.Base + serverListResponse.NextPageURL
+    if len(page) == 0 && legacy {
+        // No servers found after filtering
+        log.Println("No servers found after filtering.")
+        writeError(w, http.StatusNotFound, "No servers found after filtering.")
+        return
     }
 
-    // Filter servers based on query parameters other than "q"
-    filteredServers := allServers
-    for key, values := range queryParams {
-        // Skip filtering if the query parameter is "q" or "name"
-        if key == "q" || key == "name" {
-            continue
-        }
-        if len(values) > 0 {
-            // Special handling for "address" query parameter
-            if key == "address" {
-                // Check if the value is a domain and try to resolve it to a public IP address
-                if config.IsValidDomain(values[0]) {
-                    publicIP, err := config.GetPublicIP(values[0])
-                    if err != nil {
-                        log.Printf("Error resolving domain %s: %s", values[0], err)
-                        continue
-                    }
-                    filteredServers = filterServersByParamByKey(filteredServers, key, publicIP)
-                } else {
-                    // If not a domain, filter servers by address directly
-                    filteredServers = filterServersByParamByKey(filteredServers, key, values[0])
-                }
-            } else {
-                // For other query parameters, filter servers by matching the key with struct field tags
-                filteredServers = filterServersByParamByKey(filteredServers, key, values[0])
-            }
+    // Browsers get the details of a single server, or the list
+    if acceptsHTML(r) {
+        if len(page) == 1 {
+            renderHTML(w, page[0])
+        } else {
+            renderHTMLList(w, page)
         }
+        return
     }
 
-    // Return the result
-    if len(filteredServers) == 1 {
-        // If only one server found after filtering, return it as a single object
-        serverJSON, err := json.Marshal(filteredServers[0])
-        if err != nil {
-            log.Printf("Error marshalling server to JSON: %s", err)
-            http.Error(w, fmt.Sprintf("Error marshalling server to JSON: %s", err), http.StatusInternalServerError)
-            return
+    items := projectServers(page, options.fields)
+    if options.single {
+        // Clients that ask for it get a single server as an object, several as an array
+        var result interface{} = items
+        if len(page) == 1 {
+            result = reflect.ValueOf(items).Index(0).Interface()
         }
-        contentType := "application/json"
-        if acceptsHTML(r) {
-            contentType = "text/html"
-            // Render HTML instead of JSON
-            renderHTML(w, filteredServers[0])
-        } else {
-            w.Header().Set("Content-Type", contentType)
-            w.WriteHeader(http.StatusOK)
-            w.Write(serverJSON)
-        }
-    } else if len(filteredServers) > 1 {
-        // If multiple servers found after filtering, return them as an array
-        contentType := "application/json"
-        if acceptsHTML(r) {
-            contentType = "text/html"
-            // Render HTML instead of JSON
-            renderHTMLList(w, filteredServers)
-        } else {
-            // Return JSON
-            serverListJSON, err := json.Marshal(filteredServers)
-            if err != nil {
-                log.Printf("Error marshalling server list to JSON: %s", err)
-                http.Error(w, fmt.Sprintf("Error marshalling server list to JSON: %s", err), http.StatusInternalServerError)
-                return
-            }
-            w.Header().Set("Content-Type", contentType)
-            w.WriteHeader(http.StatusOK)
-            w.Write(serverListJSON)
-        }
-    } else {
-        // No servers found after filtering
-        log.Println("No servers found after filtering.")
-        http.Error(w, "No servers found after filtering.", http.StatusNotFound)
+        writeJSON(w, http.StatusOK, result)
+        return
     }
+    writeJSON(w, http.StatusOK, serverPage{
+        Total: len(filteredServers),
+        Items: items,
+        Next:  nextPageURL(r.URL, len(filteredServers), options),
+    })
 }
 
 // Function to render HTML for a single server
@@ -223,37 +183,3 @@ func acceptsHTML(r *http.Request) bool {
     accept := r.Header.Get("Accept")
     return strings.Contains(accept, "text/html")
 }
-
-// Define a new function to filter servers by matching the query parameter key with struct field tags
-func filterServersByParamByKey(servers []Server, key string, value string) []Server {
-    filteredServers := make([]Server, 0)
-    log.Printf("Received query parameter:\nquery: %s\nValue: %s\n", key, value)
-
-    // Iterate over each server
-    for _, server := range servers {
-        // Get the server struct type
-        serverType := reflect.TypeOf(server)
-        // Iterate over each field of the server struct
-        for i := 0; i < serverType.NumField(); i++ {
-            field := serverType.Field(i)
-            // Check if the field tag matches the query parameter key
-            if field.Tag.Get("json") == key {
-                // Get the field value
-                fieldValue := reflect.ValueOf(server).FieldByName(field.Name).Interface()
-                // Convert the field value to a string for comparison
-                fieldValueStr := fmt.Sprintf("%v", fieldValue)
-                // Log the server name and the value being compared
-                log.Printf("\nServer Name: %s\nField: %s,\nField Value: %s\nQuery Value: %s\n", server.Name, key, fieldValueStr, value)
-                // Compare the field value with the query parameter value
-                if fieldValueStr == value {
-                    filteredServers = append(filteredServers, server)
-                }
-                break // Move to the next server once a match is found
-            }
-        }
-    }
-
-    log.Printf("\nNumber of servers before filtering: %d\n", len(servers))
-    log.Printf("Number of servers after filtering: %d\n", len(filteredServers))
-    return filteredServers
-}
\ No newline at end of file
@@ -0,0 +1,182 @@
+package routes_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"palworld-query-api/internal/apitest"
+	"palworld-query-api/internal/config"
+	"palworld-query-api/internal/routes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// searchPage is the envelope ApiHandler answers with.
+type searchPage struct {
+	Total int             `json:"total"`
+	Items []routes.Server `json:"items"`
+	Next  *string         `json:"next"`
+}
+
+// startUpstream points the server list API at a mock with count servers named
+// "<prefix> 1" to "<prefix> <count>", 20 to a page, without caching or a page limit.
+func startUpstream(t *testing.T, prefix string, count int) *apitest.Handler {
+	t.Helper()
+	server, handler := apitest.NewServer(apitest.Config{Servers: apitest.GenerateServers(prefix, count)})
+	saved := config.ApiConfig
+	config.ApiConfig.Base = server.URL
+	config.ApiConfig.CacheTTL = 0
+	config.ApiConfig.MaxPages = 0
+	config.ApiConfig.Timeout = 5 * time.Second
+	t.Cleanup(func() {
+		config.ApiConfig = saved
+		server.Close()
+	})
+	return handler
+}
+
+// search calls ApiHandler with the query string and decodes the envelope of a 200 answer.
+func search(t *testing.T, query string) (*httptest.ResponseRecorder, searchPage) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	routes.ApiHandler(recorder, httptest.NewRequest(http.MethodGet, "/v1/public/search?"+query, nil))
+	var page searchPage
+	if recorder.Code == http.StatusOK {
+		if err := json.Unmarshal(recorder.Body.Bytes(), &page); err != nil {
+			t.Fatalf("decoding %s: %v", recorder.Body, err)
+		}
+	}
+	return recorder, page
+}
+
+func TestApiHandlerFollowsNextPages(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxPages int
+		total    int // servers ApiHandler gets to see
+		requests int // upstream pages fetched
+	}{
+		{"every page", 0, 45, 3},
+		{"max pages", 2, 40, 2},
+		{"more pages allowed than there are", 10, 45, 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := startUpstream(t, "Paged", 45)
+			config.ApiConfig.MaxPages = test.maxPages
+
+			recorder, page := search(t, "name=paged&limit=500")
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", recorder.Code, recorder.Body)
+			}
+			if page.Total != test.total || len(page.Items) != test.total {
+				t.Errorf("total %d with %d items, want %d", page.Total, len(page.Items), test.total)
+			}
+			if got := handler.Requests(); got != test.requests {
+				t.Errorf("fetched %d upstream pages, want %d", got, test.requests)
+			}
+		})
+	}
+}
+
+func TestApiHandlerPaging(t *testing.T) {
+	startUpstream(t, "Paging", 45)
+
+	recorder, page := search(t, "name=paging&sort=days:desc&limit=10&offset=30&api_key=secret")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", recorder.Code, recorder.Body)
+	}
+	if page.Total != 45 || len(page.Items) != 10 {
+		t.Fatalf("total %d with %d items, want 45 with 10", page.Total, len(page.Items))
+	}
+	// Days grow with the server number, so the 31st server from the top is number 15
+	if page.Items[0].Name != "Paging 15" {
+		t.Errorf("first item is %q, want Paging 15", page.Items[0].Name)
+	}
+	want := "/v1/public/search?limit=10&name=paging&offset=40&sort=days%3Adesc"
+	if page.Next == nil || *page.Next != want {
+		t.Errorf("next = %v, want %s", page.Next, want)
+	}
+
+	_, last := search(t, "name=paging&limit=10&offset=40")
+	if len(last.Items) != 5 || last.Next != nil {
+		t.Errorf("last page has %d items and next %v, want 5 and null", len(last.Items), last.Next)
+	}
+}
+
+func TestApiHandlerFilters(t *testing.T) {
+	startUpstream(t, "Filtered", 45)
+
+	tests := []struct {
+		query  string
+		status int
+		total  int
+	}{
+		{"name=filtered&region=eu", http.StatusOK, 15},
+		{"name=filtered&current_players[gte]=40", http.StatusOK, 0},
+		{"name=filtered&is_password=true&version[lt]=v0.2", http.StatusOK, 7},
+		{"name=filtered&name[contains]=ED%204", http.StatusOK, 7},
+		{"name=filtered&players=3", http.StatusBadRequest, 0},
+		{"name=filtered&days[regex]=1", http.StatusBadRequest, 0},
+		{"region=eu", http.StatusBadRequest, 0},
+	}
+	for _, test := range tests {
+		recorder, page := search(t, test.query)
+		if recorder.Code != test.status {
+			t.Errorf("%s: status %d, want %d: %s", test.query, recorder.Code, test.status, recorder.Body)
+			continue
+		}
+		if page.Total != test.total {
+			t.Errorf("%s: total %d, want %d", test.query, page.Total, test.total)
+		}
+	}
+}
+
+func TestApiHandlerCache(t *testing.T) {
+	handler := startUpstream(t, "Cached", 5)
+	config.ApiConfig.CacheTTL = time.Minute
+
+	for i, want := range []string{"MISS", "HIT"} {
+		recorder, page := search(t, "name=cached")
+		if got := recorder.Header().Get("X-Cache"); got != want || page.Total != 5 {
+			t.Errorf("search %d: X-Cache %s with %d servers, want %s with 5", i+1, got, page.Total, want)
+		}
+	}
+	if got := handler.Requests(); got != 1 {
+		t.Errorf("fetched %d upstream pages, want 1", got)
+	}
+}
+
+func TestApiHandlerResultShapes(t *testing.T) {
+	startUpstream(t, "Shaped", 12)
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		body   string
+	}{
+		{"fields", "name=shaped&name[contains]=shaped%2011&fields=name,days", http.StatusOK, `{"total":1,"items":[{"days":33,"name":"Shaped 11"}],"next":null}`},
+		{"offset past the end", "name=shaped&offset=50", http.StatusOK, `{"total":12,"items":[],"next":null}`},
+		{"single server", "name=shaped&name[contains]=shaped%2011&single=true&fields=name", http.StatusOK, `{"name":"Shaped 11"}`},
+		{"single with several", "name=shaped&name[in]=Shaped%201,Shaped%202&single=true&fields=name&sort=name:desc", http.StatusOK, `[{"name":"Shaped 2"},{"name":"Shaped 1"}]`},
+		{"single with none", "name=shaped&offset=50&single=true", http.StatusNotFound, ""},
+		{"zero limit", "name=shaped&limit=0", http.StatusBadRequest, ""},
+		{"negative limit", "name=shaped&limit=-1", http.StatusBadRequest, ""},
+		{"unknown sort", "name=shaped&sort=ping", http.StatusBadRequest, ""},
+		{"unknown field", "name=shaped&fields=name,ping", http.StatusBadRequest, ""},
+	}
+	for _, test := range tests {
+		// Not every shape is the envelope, so the body is compared as it is
+		recorder := httptest.NewRecorder()
+		routes.ApiHandler(recorder, httptest.NewRequest(http.MethodGet, "/v1/public/search?"+test.query, nil))
+		if recorder.Code != test.status {
+			t.Errorf("%s: status %d, want %d: %s", test.name, recorder.Code, test.status, recorder.Body)
+			continue
+		}
+		if test.body != "" && strings.TrimSpace(recorder.Body.String()) != test.body {
+			t.Errorf("%s: body %s, want %s", test.name, recorder.Body, test.body)
+		}
+	}
+}
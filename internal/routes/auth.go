@@ -0,0 +1,61 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"palworld-query-api/internal/config"
+	"strings"
+)
+
+type apiKeyContextKey struct{}
+
+// RequireRole only lets requests through that carry an API key with at least the given role.
+// Keys limited to some servers are also refused for the other servers' {name} routes.
+// Without any configured key, viewer routes are public and everything else is disabled.
+func RequireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !config.AuthEnabled() {
+			if role == config.RoleViewer {
+				next(w, r)
+				return
+			}
+			writeError(w, http.StatusForbidden, "Admin commands are disabled")
+			return
+		}
+
+		key := config.FindAPIKey(apiKeyOf(r))
+		if key == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="palworld-query-api"`)
+			writeError(w, http.StatusUnauthorized, "Invalid API key")
+			return
+		}
+		if !key.Allows(role) {
+			writeError(w, http.StatusForbidden, "API key needs the "+role+" role")
+			return
+		}
+		if name := r.PathValue("name"); name != "" && !key.CanAccess(name) {
+			writeError(w, http.StatusForbidden, "API key has no access to this server")
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, key)))
+	}
+}
+
+// apiKeyOf reads the key from the Authorization or X-API-Key header, or from the
+// api_key query parameter for clients such as EventSource that cannot set headers.
+func apiKeyOf(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	return r.URL.Query().Get("api_key")
+}
+
+// canAccess reports whether the request's API key may see the named server.
+// Requests without a key only get through RequireRole when auth is disabled.
+func canAccess(r *http.Request, server string) bool {
+	key, ok := r.Context().Value(apiKeyContextKey{}).(*config.APIKey)
+	return !ok || key.CanAccess(server)
+}
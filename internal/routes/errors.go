@@ -0,0 +1,144 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"palworld-query-api/internal/config"
+)
+
+// Codes of errors that are not about talking to a game server
+const (
+	codeBadRequest       = "bad_request"
+	codeUnauthorized     = "unauthorized"
+	codeForbidden        = "forbidden"
+	codeNotFound         = "not_found"
+	codeMethodNotAllowed = "method_not_allowed"
+	codeInternal         = "internal_error"
+	codeUnavailable      = "unavailable"
+	codeBadGateway       = "bad_gateway"
+	codeGatewayTimeout   = "gateway_timeout"
+)
+
+var statusCodes = map[int]string{
+	http.StatusBadRequest:          codeBadRequest,
+	http.StatusUnauthorized:        codeUnauthorized,
+	http.StatusForbidden:           codeForbidden,
+	http.StatusNotFound:            codeNotFound,
+	http.StatusMethodNotAllowed:    codeMethodNotAllowed,
+	http.StatusInternalServerError: codeInternal,
+	http.StatusServiceUnavailable:  codeUnavailable,
+	http.StatusBadGateway:          codeBadGateway,
+	http.StatusGatewayTimeout:      codeGatewayTimeout,
+}
+
+// errorResponse is the body of every error answer.
+type errorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Server    string `json:"server,omitempty"`
+	Retryable bool   `json:"retryable"`
+}
+
+// writeError answers with the JSON error envelope, coded by the HTTP status.
+func writeError(w http.ResponseWriter, status int, message string) {
+	retryable := status == http.StatusServiceUnavailable || status == http.StatusBadGateway || status == http.StatusGatewayTimeout
+	writeJSON(w, status, errorResponse{Code: statusCodes[status], Message: config.Redact(message), Retryable: retryable})
+}
+
+// writeServerNotFound answers that the named server is not configured.
+func writeServerNotFound(w http.ResponseWriter, server string) {
+	writeJSON(w, http.StatusNotFound, errorResponse{Code: codeNotFound, Message: "Server does not exist", Server: server})
+}
+
+// writeServerLookupError answers that the named server is not configured, or that
+// the config could not be read at all.
+func writeServerLookupError(w http.ResponseWriter, server string, err error) {
+	if errors.Is(err, config.ErrServerNotFound) {
+		log.Printf("Server %s does not exist", server)
+		writeServerNotFound(w, server)
+		return
+	}
+	writeConfigError(w, err)
+}
+
+// writeConfigError answers that rcon.yaml could not be read or is invalid.
+func writeConfigError(w http.ResponseWriter, err error) {
+	log.Println("Failed to read server configurations:", err)
+	writeJSON(w, http.StatusInternalServerError, errorResponse{Code: config.ErrCodeConfig, Message: config.Redact(err.Error())})
+}
+
+// writeServerError answers with a failure talking to the named server, or to the
+// public server list when server is empty:
+// 504 when it timed out, 503 while its circuit breaker is open, 500 when its config
+// is unusable and 502 otherwise.
+func writeServerError(w http.ResponseWriter, server string, err error) {
+	serverErr := config.AsServerError(server, err)
+	writeJSON(w, serverErrorStatus(serverErr.Code), errorResponse{
+		Code:      serverErr.Code,
+		Message:   config.Redact(serverErr.Error()),
+		Server:    server,
+		Retryable: serverErr.Retryable(),
+	})
+}
+
+func serverErrorStatus(code string) int {
+	switch code {
+	case config.ErrCodeTimeout:
+		return http.StatusGatewayTimeout
+	case config.ErrCodeConfig:
+		return http.StatusInternalServerError
+	case config.ErrCodeCircuitOpen:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// JSONErrors answers requests that match no route, or match one with another method,
+// with the JSON error envelope instead of the mux's plain text pages.
+func JSONErrors(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		// Let the mux decide between 404, 405 (with its Allow header) and redirects,
+		// then replace its body
+		recorder := &statusRecorder{header: w.Header()}
+		handler.ServeHTTP(recorder, r)
+		switch recorder.status {
+		case http.StatusNotFound:
+			writeError(w, http.StatusNotFound, "Not found")
+		case http.StatusMethodNotAllowed:
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		default:
+			w.WriteHeader(recorder.status)
+		}
+	})
+}
+
+// statusRecorder keeps the status and headers a handler writes and drops its body.
+type statusRecorder struct {
+	header http.Header
+	status int
+}
+
+func (s *statusRecorder) Header() http.Header {
+	return s.header
+}
+
+func (s *statusRecorder) Write(data []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return len(data), nil
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+}
@@ -0,0 +1,103 @@
+package routes_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"palworld-query-api/internal/config"
+	"palworld-query-api/internal/rcontest"
+	"palworld-query-api/internal/routes"
+	"path/filepath"
+	"testing"
+)
+
+// errorEnvelope is the JSON body of every error answer.
+type errorEnvelope struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Server    string `json:"server"`
+	Retryable bool   `json:"retryable"`
+}
+
+// useServerConfig points the shared config store at an rcon.yaml with the given content.
+func useServerConfig(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rcon.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.ServerConfigs.Start(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		config.ServerConfigs.Close()
+		config.Pool.Retain(nil)
+		config.Breakers.Retain(nil)
+	})
+}
+
+func TestJSONErrors(t *testing.T) {
+	refusing, err := rcontest.NewServer(rcontest.Config{Password: "secret", Faults: rcontest.Faults{AuthFailure: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { refusing.Close() })
+	useServerConfig(t, "dead:\n  address: 127.0.0.1:1\n  password: secret\n  timeout: 1s\n"+
+		"refusing:\n  address: "+refusing.Addr()+"\n  password: secret\n  timeout: 1s\n")
+	savedKeys := config.APIKeys
+	config.APIKeys = nil
+	t.Cleanup(func() { config.APIKeys = savedKeys })
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(config.Routes.Servers, routes.RequireRole(config.RoleViewer, routes.ServersHandler))
+	mux.HandleFunc(config.Routes.Server, routes.RequireRole(config.RoleViewer, routes.ServerHandler))
+	mux.HandleFunc(config.Routes.RconBroadcast, routes.RequireRole(config.RoleModerator, routes.BroadcastHandler))
+	handler := routes.JSONErrors(mux)
+
+	tests := []struct {
+		name      string
+		method    string
+		target    string
+		status    int
+		code      string
+		server    string
+		retryable bool
+		allow     string // expected Allow header, empty when there should be none
+	}{
+		{"unknown route", http.MethodGet, "/nope", http.StatusNotFound, "not_found", "", false, ""},
+		{"wrong method", http.MethodDelete, "/v1/servers", http.StatusMethodNotAllowed, "method_not_allowed", "", false, "GET, HEAD"},
+		{"wrong method on a POST route", http.MethodGet, "/rcon/dead/broadcast", http.StatusMethodNotAllowed, "method_not_allowed", "", false, "POST"},
+		{"unknown server", http.MethodGet, "/v1/servers/ghost", http.StatusNotFound, "not_found", "ghost", false, ""},
+		{"admin commands without API keys", http.MethodPost, "/rcon/dead/broadcast", http.StatusForbidden, "forbidden", "", false, ""},
+		{"unreachable server", http.MethodGet, "/v1/servers/dead?fresh=true", http.StatusBadGateway, config.ErrCodeDial, "dead", true, ""},
+		{"refused password", http.MethodGet, "/v1/servers/refusing?fresh=true", http.StatusBadGateway, config.ErrCodeAuth, "refusing", false, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(test.method, test.target, nil))
+			if recorder.Code != test.status {
+				t.Fatalf("status %d, want %d: %s", recorder.Code, test.status, recorder.Body)
+			}
+			if contentType := recorder.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("Content-Type %q, want application/json", contentType)
+			}
+			if allow := recorder.Header().Get("Allow"); allow != test.allow {
+				t.Errorf("Allow %q, want %q", allow, test.allow)
+			}
+
+			var got errorEnvelope
+			if err := json.Unmarshal(recorder.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decoding %s: %v", recorder.Body, err)
+			}
+			if got.Code != test.code || got.Server != test.server || got.Retryable != test.retryable {
+				t.Errorf("code %q for server %q, retryable %v, want %q for %q, %v",
+					got.Code, got.Server, got.Retryable, test.code, test.server, test.retryable)
+			}
+			if got.Message == "" {
+				t.Error("error has no message")
+			}
+		})
+	}
+}
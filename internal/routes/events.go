@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"net/http"
+	"palworld-query-api/internal/config"
+	"strconv"
+)
+
+func EventsHandler(w http.ResponseWriter, r *http.Request) {
+	serverName := r.PathValue("name")
+	if _, err := config.GetServerConfig(serverName); err != nil {
+		writeServerLookupError(w, serverName, err)
+		return
+	}
+
+	queryParams := r.URL.Query()
+	since, err := parseUintParam(queryParams.Get("since"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "since must be an event id")
+		return
+	}
+	limit, err := parseUintParam(queryParams.Get("limit"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "limit must be a positive number")
+		return
+	}
+
+	events := config.Events.Recent(serverName, since, int(limit))
+	writeJSON(w, http.StatusOK, map[string]interface{}{"server": serverName, "events": events})
+}
+
+// parseUintParam parses an optional non-negative query parameter, defaulting to 0.
+func parseUintParam(value string) (uint64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
@@ -0,0 +1,289 @@
+package routes
+
+import (
+	"fmt"
+	"net/url"
+	"palworld-query-api/internal/config"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Filter operators, written as field[op]=value. A bare field=value means eq.
+const (
+	opEq       = "eq"
+	opNe       = "ne"
+	opLt       = "lt"
+	opLte      = "lte"
+	opGt       = "gt"
+	opGte      = "gte"
+	opContains = "contains" // case-insensitive substring, strings only
+	opIn       = "in"       // comma separated list of accepted values
+	opRegex    = "regex"    // RE2 syntax, strings only
+)
+
+// searchParams are the /api query parameters that are not filters.
+var searchParams = map[string]bool{
+	"q": true, "name": true, "api_key": true,
+	"sort": true, "limit": true, "offset": true, "fields": true, "single": true,
+}
+
+// serverFilter is one parsed filter on a Server field.
+type serverFilter struct {
+	field  string
+	index  int
+	kind   reflect.Kind
+	op     string
+	values []string // in needs several, everything else exactly one
+	ints   []int64
+	bools  []bool
+	regex  *regexp.Regexp
+}
+
+// serverFields maps the JSON names of Server's fields to their index.
+var serverFields = func() map[string]int {
+	fields := make(map[string]int)
+	serverType := reflect.TypeOf(Server{})
+	for i := 0; i < serverType.NumField(); i++ {
+		fields[serverType.Field(i).Tag.Get("json")] = i
+	}
+	return fields
+}()
+
+// parseFilters turns the filter query parameters into filters, in a stable order.
+// Unknown fields, operators or values that don't fit the field's type are errors.
+func parseFilters(params url.Values) ([]serverFilter, error) {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if !searchParams[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var filters []serverFilter
+	for _, key := range keys {
+		for _, value := range params[key] {
+			filter, err := parseFilter(key, value)
+			if err != nil {
+				return nil, err
+			}
+			filters = append(filters, filter)
+		}
+	}
+	return filters, nil
+}
+
+func parseFilter(key string, value string) (serverFilter, error) {
+	field, op := key, opEq
+	if open := strings.Index(key, "["); open >= 0 && strings.HasSuffix(key, "]") {
+		field, op = key[:open], key[open+1:len(key)-1]
+	}
+	index, ok := serverFields[field]
+	if !ok {
+		return serverFilter{}, fmt.Errorf("unknown filter field '%s'", field)
+	}
+	filter := serverFilter{field: field, index: index, kind: reflect.TypeOf(Server{}).Field(index).Type.Kind(), op: op}
+
+	switch op {
+	case opEq, opNe:
+		filter.values = []string{value}
+	case opIn:
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				filter.values = append(filter.values, item)
+			}
+		}
+		if len(filter.values) == 0 {
+			return serverFilter{}, fmt.Errorf("filter %s needs at least one value", key)
+		}
+	case opLt, opLte, opGt, opGte:
+		if filter.kind == reflect.Bool || (filter.kind == reflect.String && field != "version") {
+			return serverFilter{}, fmt.Errorf("filter %s: %s only compares numbers and versions", key, op)
+		}
+		filter.values = []string{value}
+	case opContains, opRegex:
+		if filter.kind != reflect.String {
+			return serverFilter{}, fmt.Errorf("filter %s: %s only applies to text fields", key, op)
+		}
+		filter.values = []string{value}
+		if op == opRegex {
+			regex, err := regexp.Compile(value)
+			if err != nil {
+				return serverFilter{}, fmt.Errorf("filter %s: invalid regex: %v", key, err)
+			}
+			filter.regex = regex
+		}
+	default:
+		return serverFilter{}, fmt.Errorf("unknown filter operator '%s' in %s", op, key)
+	}
+
+	// Parse numbers and booleans once, so they are compared by value
+	for _, value := range filter.values {
+		switch filter.kind {
+		case reflect.Int, reflect.Int64:
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return serverFilter{}, fmt.Errorf("filter %s: '%s' is not a number", key, value)
+			}
+			filter.ints = append(filter.ints, parsed)
+		case reflect.Bool:
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return serverFilter{}, fmt.Errorf("filter %s: '%s' is not true or false", key, value)
+			}
+			filter.bools = append(filter.bools, parsed)
+		}
+	}
+	return filter, nil
+}
+
+// resolveAddress lets address filters name a domain, compared by its public IP.
+func (f *serverFilter) resolveAddress() error {
+	if f.field != "address" || (f.op != opEq && f.op != opNe && f.op != opIn) {
+		return nil
+	}
+	for i, value := range f.values {
+		if !config.IsValidDomain(value) {
+			continue
+		}
+		publicIP, err := config.GetPublicIP(value)
+		if err != nil {
+			return fmt.Errorf("resolving domain %s: %w", value, err)
+		}
+		f.values[i] = publicIP
+	}
+	return nil
+}
+
+// matches reports whether the server passes the filter.
+func (f *serverFilter) matches(server Server) bool {
+	field := reflect.ValueOf(server).Field(f.index)
+	switch f.kind {
+	case reflect.Int, reflect.Int64:
+		return f.matchesInt(field.Int())
+	case reflect.Bool:
+		return f.matchesBool(field.Bool())
+	default:
+		return f.matchesString(field.String())
+	}
+}
+
+func (f *serverFilter) matchesInt(value int64) bool {
+	switch f.op {
+	case opEq:
+		return value == f.ints[0]
+	case opNe:
+		return value != f.ints[0]
+	case opLt:
+		return value < f.ints[0]
+	case opLte:
+		return value <= f.ints[0]
+	case opGt:
+		return value > f.ints[0]
+	case opGte:
+		return value >= f.ints[0]
+	case opIn:
+		for _, accepted := range f.ints {
+			if value == accepted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (f *serverFilter) matchesBool(value bool) bool {
+	switch f.op {
+	case opEq:
+		return value == f.bools[0]
+	case opNe:
+		return value != f.bools[0]
+	case opIn:
+		for _, accepted := range f.bools {
+			if value == accepted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (f *serverFilter) matchesString(value string) bool {
+	switch f.op {
+	case opEq:
+		return value == f.values[0]
+	case opNe:
+		return value != f.values[0]
+	case opLt:
+		return compareVersions(value, f.values[0]) < 0
+	case opLte:
+		return compareVersions(value, f.values[0]) <= 0
+	case opGt:
+		return compareVersions(value, f.values[0]) > 0
+	case opGte:
+		return compareVersions(value, f.values[0]) >= 0
+	case opContains:
+		return strings.Contains(strings.ToLower(value), strings.ToLower(f.values[0]))
+	case opIn:
+		for _, accepted := range f.values {
+			if strings.EqualFold(value, accepted) {
+				return true
+			}
+		}
+	case opRegex:
+		return f.regex.MatchString(value)
+	}
+	return false
+}
+
+// compareVersions compares versions such as v0.1.5.1 part by part, numerically where
+// both parts are numbers. Missing parts count as 0, so v0.2 equals v0.2.0.0.
+func compareVersions(a string, b string) int {
+	partsA := strings.Split(strings.TrimPrefix(strings.ToLower(a), "v"), ".")
+	partsB := strings.Split(strings.TrimPrefix(strings.ToLower(b), "v"), ".")
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		partA, partB := "0", "0"
+		if i < len(partsA) {
+			partA = partsA[i]
+		}
+		if i < len(partsB) {
+			partB = partsB[i]
+		}
+		numberA, errA := strconv.Atoi(partA)
+		numberB, errB := strconv.Atoi(partB)
+		if errA == nil && errB == nil {
+			if numberA != numberB {
+				if numberA < numberB {
+					return -1
+				}
+				return 1
+			}
+			continue
+		}
+		if partA != partB {
+			return strings.Compare(partA, partB)
+		}
+	}
+	return 0
+}
+
+// filterServers keeps the servers that pass every filter.
+func filterServers(servers []Server, filters []serverFilter) []Server {
+	filtered := make([]Server, 0, len(servers))
+	for _, server := range servers {
+		keep := true
+		for i := range filters {
+			if !filters[i].matches(server) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			filtered = append(filtered, server)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,154 @@
+package routes
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+var filterTestServer = Server{
+	Name:           "Alpha EU #1",
+	Region:         "EU",
+	Address:        "203.0.113.7",
+	Port:           8211,
+	IsPassword:     true,
+	Version:        "v0.1.10.0",
+	CurrentPlayers: 12,
+	MaxPlayers:     32,
+}
+
+func TestFilterOperators(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		match bool
+	}{
+		// Strings
+		{"region", "EU", true},
+		{"region", "eu", false},
+		{"region[ne]", "NA", true},
+		{"name[contains]", "eu #", true},
+		{"name[contains]", "asia", false},
+		{"region[in]", "na, eu", true},
+		{"region[in]", "na,,asia", false},
+		{"name[regex]", `^Alpha .* #\d+$`, true},
+		{"name[regex]", `^alpha`, false},
+		{"name[regex]", `(?i)^alpha`, true},
+		// Numbers
+		{"port", "8211", true},
+		{"port[ne]", "8211", false},
+		{"current_players[lt]", "12", false},
+		{"current_players[lte]", "12", true},
+		{"current_players[gt]", "11", true},
+		{"current_players[gte]", "13", false},
+		{"max_players[in]", "16,32", true},
+		{"max_players[in]", "16, 64", false},
+		{"current_players", "012", true},
+		// Booleans
+		{"is_password", "true", true},
+		{"is_password", "0", false},
+		{"is_password[ne]", "false", true},
+		{"is_password[in]", "false,1", true},
+		// Versions
+		{"version", "v0.1.10.0", true},
+		{"version[gt]", "v0.1.9", true},
+		{"version[lt]", "v0.2", true},
+		{"version[gte]", "V0.1.10", true},
+		{"version[lte]", "v0.1.10.0.0", true},
+	}
+	for _, test := range tests {
+		filter, err := parseFilter(test.key, test.value)
+		if err != nil {
+			t.Errorf("%s=%s: %v", test.key, test.value, err)
+			continue
+		}
+		if got := filter.matches(filterTestServer); got != test.match {
+			t.Errorf("%s=%s matches = %v, want %v", test.key, test.value, got, test.match)
+		}
+	}
+}
+
+func TestFilterErrors(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		err   string
+	}{
+		{"players", "3", "unknown filter field 'players'"},
+		{"port[between]", "1", "unknown filter operator 'between'"},
+		{"port", "many", "'many' is not a number"},
+		{"port[in]", "1,two", "'two' is not a number"},
+		{"is_password", "yes", "'yes' is not true or false"},
+		{"region[in]", " , ", "needs at least one value"},
+		{"name[lt]", "b", "only compares numbers and versions"},
+		{"is_password[gt]", "false", "only compares numbers and versions"},
+		{"port[contains]", "82", "only applies to text fields"},
+		{"is_password[regex]", "t.*", "only applies to text fields"},
+		{"name[regex]", "(unclosed", "invalid regex"},
+	}
+	for _, test := range tests {
+		_, err := parseFilter(test.key, test.value)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s=%s: error %v, want %q", test.key, test.value, err, test.err)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v0.2", "v0.2.0.0", 0},
+		{"v0.2.0.1", "v0.2", 1},
+		{"V0.1.5.1", "v0.1.5.1", 0},
+		{"0.1.5", "v0.1.5", 0},
+		{"v0.1.10", "v0.1.9", 1}, // numeric, not lexical
+		{"v0.1.9", "v0.1.10", -1},
+		{"v0.1.01", "v0.1.1", 0},
+		{"v0.1.beta", "v0.1.alpha", 1}, // lexical where a part is not a number
+		{"v0.1.2b", "v0.1.2a", 1},
+		{"v0.1.2", "v0.1.beta", -1},
+		{"", "v0", -1}, // servers that report no version come first
+	}
+	for _, test := range tests {
+		if got := compareVersions(test.a, test.b); got != test.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+		if got := compareVersions(test.b, test.a); got != -test.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", test.b, test.a, got, -test.want)
+		}
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	params := url.Values{
+		"region":               {"EU"},
+		"current_players[gte]": {"10", "12"},
+		"name":                 {"alpha"},
+		"sort":                 {"days:desc"},
+		"limit":                {"5"},
+		"api_key":              {"secret"},
+	}
+	filters, err := parseFilters(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, filter := range filters {
+		got = append(got, filter.field+"["+filter.op+"]="+strings.Join(filter.values, ","))
+	}
+	want := "current_players[gte]=10 current_players[gte]=12 region[eq]=EU"
+	if strings.Join(got, " ") != want {
+		t.Errorf("filters %v, want %s", got, want)
+	}
+
+	servers := filterServers([]Server{filterTestServer, {Region: "EU", CurrentPlayers: 11}, {Region: "NA", CurrentPlayers: 20}}, filters)
+	if len(servers) != 1 || servers[0].Name != filterTestServer.Name {
+		t.Errorf("filterServers kept %v, want only %s", servers, filterTestServer.Name)
+	}
+
+	if _, err := parseFilters(url.Values{"region": {"EU"}, "port": {"x"}}); err == nil {
+		t.Error("parseFilters accepted a bad value")
+	}
+}
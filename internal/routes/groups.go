@@ -0,0 +1,53 @@
+package routes
+
+import (
+	"log"
+	"net/http"
+	"palworld-query-api/internal/config"
+)
+
+// groupSummary is the status of every server of a group, summed up.
+type groupSummary struct {
+	Group         string                        `json:"group"`
+	TotalServers  int                           `json:"total_servers"`
+	ServersOnline int                           `json:"servers_online"`
+	TotalPlayers  int                           `json:"total_players"`
+	Servers       map[string]*config.ServerInfo `json:"servers"`
+}
+
+// GroupHandler serves the servers of the group named in the path that the API key can see,
+// with their total players and how many are online. A group without such servers is 404.
+func GroupHandler(w http.ResponseWriter, r *http.Request) {
+	group := r.PathValue("group")
+	configured, err := config.GetConfig()
+	if err != nil {
+		writeConfigError(w, err)
+		return
+	}
+
+	servers := make(map[string]config.ConfigServer)
+	for name, server := range configured {
+		if server.InGroup(group) && canAccess(r, name) && matchesMeta(r, server) {
+			servers[name] = server
+		}
+	}
+	if len(servers) == 0 {
+		writeError(w, http.StatusNotFound, "Group does not exist")
+		return
+	}
+
+	serverDataMap, updatedAt := getAllServerData(r, servers)
+	summary := groupSummary{Group: group, TotalServers: len(servers), Servers: make(map[string]*config.ServerInfo, len(servers))}
+	for name, info := range serverDataMap {
+		served := servedInfo(servers[name], info)
+		summary.Servers[name] = served
+		if served.Online {
+			summary.ServersOnline++
+			summary.TotalPlayers += served.Players.Count
+		}
+	}
+
+	setCacheHeaders(w, updatedAt)
+	writeJSON(w, http.StatusOK, summary)
+	log.Printf("Sent group %s to client", group)
+}
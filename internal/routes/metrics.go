@@ -0,0 +1,81 @@
+package routes
+
+import (
+	"net/http"
+	"palworld-query-api/internal/config"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "palworld_http_requests_total",
+		Help: "HTTP requests handled, by route.",
+	}, []string{"route", "method", "code"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "palworld_http_request_duration_seconds",
+		Help:    "Latency of HTTP requests, by route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+)
+
+func init() {
+	config.Registry.MustRegister(httpRequests, httpDuration)
+}
+
+// MetricsHandler serves the registered metrics in the Prometheus text format. API keys
+// limited to some servers only get the series of those servers.
+func MetricsHandler() http.HandlerFunc {
+	all := promhttp.HandlerFor(config.Registry, promhttp.HandlerOpts{})
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, ok := r.Context().Value(apiKeyContextKey{}).(*config.APIKey)
+		if !ok || len(key.Servers) == 0 {
+			all.ServeHTTP(w, r)
+			return
+		}
+		scoped := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			families, err := config.Registry.Gather()
+			return visibleMetrics(families, key), err
+		})
+		promhttp.HandlerFor(scoped, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	}
+}
+
+// visibleMetrics drops the series labeled with a server the key can't access.
+// Families left without series are dropped too.
+func visibleMetrics(families []*dto.MetricFamily, key *config.APIKey) []*dto.MetricFamily {
+	var visible []*dto.MetricFamily
+	for _, family := range families {
+		var metrics []*dto.Metric
+		for _, metric := range family.Metric {
+			if server, ok := metricServer(metric); !ok || key.CanAccess(server) {
+				metrics = append(metrics, metric)
+			}
+		}
+		if len(metrics) > 0 {
+			family.Metric = metrics
+			visible = append(visible, family)
+		}
+	}
+	return visible
+}
+
+// metricServer returns the value of the series' server label, if it has one.
+func metricServer(metric *dto.Metric) (string, bool) {
+	for _, label := range metric.Label {
+		if label.GetName() == "server" {
+			return label.GetValue(), true
+		}
+	}
+	return "", false
+}
+
+// Instrument counts and times the requests handled by next under the given route label.
+func Instrument(route string, next http.HandlerFunc) http.HandlerFunc {
+	labels := prometheus.Labels{"route": route}
+	return promhttp.InstrumentHandlerDuration(httpDuration.MustCurryWith(labels),
+		promhttp.InstrumentHandlerCounter(httpRequests.MustCurryWith(labels), next))
+}
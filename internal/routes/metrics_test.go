@@ -0,0 +1,59 @@
+package routes_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"palworld-query-api/internal/config"
+	"palworld-query-api/internal/routes"
+	"strings"
+	"testing"
+)
+
+func TestMetricsHandlerScoping(t *testing.T) {
+	savedKeys := config.APIKeys
+	config.APIKeys = []config.APIKey{
+		{Name: "all", Key: "all-key", Role: config.RoleViewer},
+		{Name: "beta", Key: "beta-key", Role: config.RoleViewer, Servers: []string{"beta"}},
+	}
+	// Querying the breakers makes them show up in the metrics
+	config.Breakers.State("alpha")
+	config.Breakers.State("beta")
+	t.Cleanup(func() {
+		config.APIKeys = savedKeys
+		config.Breakers.Retain(nil)
+	})
+	handler := routes.RequireRole(config.RoleViewer, routes.MetricsHandler())
+
+	tests := []struct {
+		key   string
+		shown []string
+		kept  []string
+	}{
+		{"all-key", []string{`server="alpha"`, `server="beta"`}, nil},
+		{"beta-key", []string{`server="beta"`}, []string{`server="alpha"`}},
+	}
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		request.Header.Set("X-API-Key", test.key)
+		recorder := httptest.NewRecorder()
+		handler(recorder, request)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", test.key, recorder.Code, recorder.Body)
+		}
+		body := recorder.Body.String()
+		for _, series := range test.shown {
+			if !strings.Contains(body, series) {
+				t.Errorf("%s: no series with %s", test.key, series)
+			}
+		}
+		for _, series := range test.kept {
+			if strings.Contains(body, series) {
+				t.Errorf("%s: shows series with %s", test.key, series)
+			}
+		}
+		// Series without a server label stay visible
+		if !strings.Contains(body, "go_goroutines") {
+			t.Errorf("%s: process metrics are missing", test.key)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package routes
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Page sizes of /api results
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// serverPage is the envelope of /api results. Next is the URL of the following page,
+// null on the last one.
+type serverPage struct {
+	Total int         `json:"total"`
+	Items interface{} `json:"items"`
+	Next  *string     `json:"next"`
+}
+
+// sortKey orders servers by one field.
+type sortKey struct {
+	field string
+	index int
+	desc  bool
+}
+
+// resultOptions are the /api parameters that shape the result rather than filter it.
+type resultOptions struct {
+	sort   []sortKey
+	limit  int
+	offset int
+	fields []string
+	single bool
+}
+
+// parseResultOptions reads sort, limit, offset, fields and single.
+func parseResultOptions(params url.Values) (resultOptions, error) {
+	options := resultOptions{limit: defaultPageLimit}
+
+	if value := params.Get("sort"); value != "" {
+		for _, item := range strings.Split(value, ",") {
+			field, direction, _ := strings.Cut(strings.TrimSpace(item), ":")
+			index, ok := serverFields[field]
+			if !ok {
+				return options, fmt.Errorf("unknown sort field '%s'", field)
+			}
+			if direction != "" && direction != "asc" && direction != "desc" {
+				return options, fmt.Errorf("sort direction of %s must be asc or desc", field)
+			}
+			options.sort = append(options.sort, sortKey{field: field, index: index, desc: direction == "desc"})
+		}
+	}
+
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return options, fmt.Errorf("limit must be a number from 1 to %d", maxPageLimit)
+		}
+		options.limit = limit
+	}
+	if value := params.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return options, fmt.Errorf("offset must be a number from 0")
+		}
+		options.offset = offset
+	}
+
+	if value := params.Get("fields"); value != "" {
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			if _, ok := serverFields[field]; !ok {
+				return options, fmt.Errorf("unknown field '%s'", field)
+			}
+			options.fields = append(options.fields, field)
+		}
+	}
+
+	if value := params.Get("single"); value != "" {
+		single, err := strconv.ParseBool(value)
+		if err != nil {
+			return options, fmt.Errorf("single must be true or false")
+		}
+		options.single = single
+	}
+	return options, nil
+}
+
+// sortServers orders servers by the sort keys in turn, keeping the upstream order of ties.
+func sortServers(servers []Server, keys []sortKey) {
+	if len(keys) == 0 {
+		return
+	}
+	sort.SliceStable(servers, func(i, j int) bool {
+		a, b := reflect.ValueOf(servers[i]), reflect.ValueOf(servers[j])
+		for _, key := range keys {
+			compared := compareField(key.field, a.Field(key.index), b.Field(key.index))
+			if compared == 0 {
+				continue
+			}
+			if key.desc {
+				return compared > 0
+			}
+			return compared < 0
+		}
+		return false
+	})
+}
+
+// compareField compares two values of a Server field by their type.
+func compareField(field string, a reflect.Value, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int64:
+		switch {
+		case a.Int() < b.Int():
+			return -1
+		case a.Int() > b.Int():
+			return 1
+		}
+		return 0
+	case reflect.Bool:
+		switch {
+		case a.Bool() == b.Bool():
+			return 0
+		case b.Bool():
+			return -1
+		}
+		return 1
+	default:
+		if field == "version" {
+			return compareVersions(a.String(), b.String())
+		}
+		return strings.Compare(strings.ToLower(a.String()), strings.ToLower(b.String()))
+	}
+}
+
+// pageOf cuts the page out of servers.
+func pageOf(servers []Server, options resultOptions) []Server {
+	start := options.offset
+	if start > len(servers) {
+		start = len(servers)
+	}
+	end := start + options.limit
+	if end > len(servers) {
+		end = len(servers)
+	}
+	return servers[start:end]
+}
+
+// nextPageURL is the request URL with the offset of the following page, or nil after the last.
+func nextPageURL(requestURL *url.URL, total int, options resultOptions) *string {
+	offset := options.offset + options.limit
+	if offset >= total {
+		return nil
+	}
+	params := requestURL.Query()
+	params.Set("offset", strconv.Itoa(offset))
+	params.Set("limit", strconv.Itoa(options.limit))
+	// Don't hand the API key on in links that may end up in logs or browser history
+	params.Del("api_key")
+	next := requestURL.Path + "?" + params.Encode()
+	return &next
+}
+
+// projectServers keeps only the given fields of each server, or the whole servers without fields.
+func projectServers(servers []Server, fields []string) interface{} {
+	if len(fields) == 0 {
+		return servers
+	}
+	items := make([]map[string]interface{}, 0, len(servers))
+	for _, server := range servers {
+		value := reflect.ValueOf(server)
+		item := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			item[field] = value.Field(serverFields[field]).Interface()
+		}
+		items = append(items, item)
+	}
+	return items
+}
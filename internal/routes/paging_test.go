@@ -0,0 +1,125 @@
+package routes
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseResultOptions(t *testing.T) {
+	tests := []struct {
+		query string
+		err   string // empty when the options are valid
+	}{
+		{"", ""},
+		{"sort=days:desc,name&limit=500&offset=0&fields=name,days&single=false", ""},
+		{"sort=players", "unknown sort field 'players'"},
+		{"sort=days:down", "sort direction of days must be asc or desc"},
+		{"limit=0", "limit must be a number from 1 to 500"},
+		{"limit=-5", "limit must be a number from 1 to 500"},
+		{"limit=501", "limit must be a number from 1 to 500"},
+		{"limit=ten", "limit must be a number from 1 to 500"},
+		{"offset=-1", "offset must be a number from 0"},
+		{"fields=name,players", "unknown field 'players'"},
+		{"single=maybe", "single must be true or false"},
+	}
+	for _, test := range tests {
+		params, _ := url.ParseQuery(test.query)
+		_, err := parseResultOptions(params)
+		if test.err == "" && err != nil {
+			t.Errorf("%s: %v", test.query, err)
+		} else if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%s: error %v, want %q", test.query, err, test.err)
+		}
+	}
+
+	options, _ := parseResultOptions(url.Values{})
+	if options.limit != defaultPageLimit || options.offset != 0 || options.single || options.sort != nil || options.fields != nil {
+		t.Errorf("defaults = %+v", options)
+	}
+}
+
+func TestSortServers(t *testing.T) {
+	servers := []Server{
+		{Name: "b", Days: 2, Version: "v0.1.10", IsPassword: true},
+		{Name: "A", Days: 1, Version: "v0.1.9"},
+		{Name: "c", Days: 2, Version: "v0.2"},
+		{Name: "d", Days: 1, Version: "v0.1.9", IsPassword: true},
+	}
+	tests := []struct {
+		sort string
+		want string
+	}{
+		{"", "b A c d"},
+		{"name", "A b c d"}, // case-insensitive
+		{"name:desc", "d c b A"},
+		{"days", "A d b c"}, // ties keep the upstream order
+		{"days:desc", "b c A d"},
+		{"version", "A d b c"}, // by version, not lexically
+		{"is_password,name:desc", "c A d b"},
+		{"days:desc,version:desc", "c b A d"},
+	}
+	for _, test := range tests {
+		options, err := parseResultOptions(url.Values{"sort": {test.sort}})
+		if err != nil {
+			t.Fatalf("%s: %v", test.sort, err)
+		}
+		sorted := append([]Server(nil), servers...)
+		sortServers(sorted, options.sort)
+		var names []string
+		for _, server := range sorted {
+			names = append(names, server.Name)
+		}
+		if got := strings.Join(names, " "); got != test.want {
+			t.Errorf("sort=%s: %s, want %s", test.sort, got, test.want)
+		}
+	}
+}
+
+func TestPageOf(t *testing.T) {
+	servers := make([]Server, 7)
+	for i := range servers {
+		servers[i].Days = i
+	}
+	tests := []struct {
+		offset, limit int
+		first, count  int
+		next          string // offset of the next page, empty on the last
+	}{
+		{0, 3, 0, 3, "3"},
+		{3, 3, 3, 3, "6"},
+		{6, 3, 6, 1, ""},
+		{7, 3, 0, 0, ""},
+		{100, 3, 0, 0, ""},
+		{0, 7, 0, 7, ""},
+		{0, 500, 0, 7, ""},
+	}
+	for _, test := range tests {
+		options := resultOptions{offset: test.offset, limit: test.limit}
+		page := pageOf(servers, options)
+		if len(page) != test.count || (test.count > 0 && page[0].Days != test.first) {
+			t.Errorf("offset %d limit %d: %d servers, want %d from %d", test.offset, test.limit, len(page), test.count, test.first)
+		}
+
+		requestURL, _ := url.Parse("/v1/public/search?name=x&api_key=secret")
+		next := nextPageURL(requestURL, len(servers), options)
+		switch {
+		case test.next == "" && next != nil:
+			t.Errorf("offset %d limit %d: next %s, want none", test.offset, test.limit, *next)
+		case test.next != "" && (next == nil || !strings.Contains(*next, "offset="+test.next) || strings.Contains(*next, "api_key")):
+			t.Errorf("offset %d limit %d: next %v, want offset %s without the API key", test.offset, test.limit, next, test.next)
+		}
+	}
+}
+
+func TestProjectServers(t *testing.T) {
+	servers := []Server{{Name: "Alpha", Days: 3, Region: "eu"}}
+	if got := projectServers(servers, nil); !reflect.DeepEqual(got, servers) {
+		t.Errorf("without fields: %v, want the whole servers", got)
+	}
+	want := []map[string]interface{}{{"name": "Alpha", "days": 3}}
+	if got := projectServers(servers, []string{"name", "days"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("with fields: %v, want %v", got, want)
+	}
+}
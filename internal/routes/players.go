@@ -0,0 +1,125 @@
+package routes
+
+import (
+	"log"
+	"net/http"
+	"palworld-query-api/internal/config"
+)
+
+// Pagination defaults for player history
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+func PlayerHandler(w http.ResponseWriter, r *http.Request) {
+	if config.History == nil {
+		writeError(w, http.StatusServiceUnavailable, "Player history is disabled")
+		return
+	}
+
+	steamID := r.PathValue("steamid")
+	history, err := config.History.Player(steamID)
+	if err != nil {
+		log.Printf("Error reading player history for %s: %v", steamID, err)
+		writeError(w, http.StatusInternalServerError, "Error reading player history")
+		return
+	}
+	if history != nil {
+		history = visibleHistory(r, history)
+	}
+	if history == nil {
+		writeError(w, http.StatusNotFound, "Player has never been seen")
+		return
+	}
+	writeJSON(w, http.StatusOK, history)
+}
+
+func PlayersHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if config.History == nil {
+		writeError(w, http.StatusServiceUnavailable, "Player history is disabled")
+		return
+	}
+
+	serverName := r.PathValue("name")
+	if _, err := config.GetServerConfig(serverName); err != nil {
+		writeServerLookupError(w, serverName, err)
+		return
+	}
+
+	queryParams := r.URL.Query()
+	offset, err := parseUintParam(queryParams.Get("offset"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "offset must be a positive number")
+		return
+	}
+	limit, err := parseUintParam(queryParams.Get("limit"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "limit must be a positive number")
+		return
+	}
+	if limit == 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	records, total, err := config.History.ServerPlayers(serverName, int(offset), int(limit))
+	if err != nil {
+		log.Printf("Error reading player history for %s: %v", serverName, err)
+		writeError(w, http.StatusInternalServerError, "Error reading player history")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"server": serverName,
+		"total":  total,
+		"offset": offset,
+		"limit":  limit,
+		"items":  records,
+	})
+}
+
+// visibleHistory drops the servers the request's API key can't see. When some are
+// hidden, the overall record is rebuilt from the visible ones, so its playtime and
+// sessions don't count time on the hidden servers.
+func visibleHistory(r *http.Request, history *config.PlayerHistory) *config.PlayerHistory {
+	servers := []config.PlayerRecord{}
+	for _, record := range history.Servers {
+		if canAccess(r, record.Server) {
+			servers = append(servers, record)
+		}
+	}
+	if len(servers) == 0 {
+		return nil
+	}
+	if len(servers) < len(history.Servers) {
+		return &config.PlayerHistory{PlayerRecord: overallRecord(servers), Servers: servers}
+	}
+	visible := *history
+	if !canAccess(r, visible.Server) {
+		visible.Server = ""
+	}
+	return &visible
+}
+
+// overallRecord combines per-server records of a player: the totals are summed and
+// the name, server and online state come from the server the player was seen on last.
+func overallRecord(records []config.PlayerRecord) config.PlayerRecord {
+	latest := records[0]
+	overall := config.PlayerRecord{SteamID: latest.SteamID, FirstSeen: latest.FirstSeen}
+	for _, record := range records {
+		if record.LastSeen.After(latest.LastSeen) {
+			latest = record
+		}
+		if record.FirstSeen.Before(overall.FirstSeen) {
+			overall.FirstSeen = record.FirstSeen
+		}
+		overall.Playtime += record.Playtime
+		overall.Sessions += record.Sessions
+	}
+	overall.Name, overall.PID, overall.Server = latest.Name, latest.PID, latest.Server
+	overall.LastSeen = latest.LastSeen
+	overall.Online, overall.OnlineSince = latest.Online, latest.OnlineSince
+	return overall
+}
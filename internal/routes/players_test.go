@@ -0,0 +1,72 @@
+package routes_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"palworld-query-api/internal/config"
+	"palworld-query-api/internal/routes"
+	"testing"
+	"time"
+)
+
+func TestPlayerHandlerScoping(t *testing.T) {
+	history, err := config.OpenHistory(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	savedHistory, savedKeys := config.History, config.APIKeys
+	config.History = history
+	config.APIKeys = []config.APIKey{
+		{Name: "all", Key: "all-key", Role: config.RoleViewer},
+		{Name: "beta", Key: "beta-key", Role: config.RoleViewer, Servers: []string{"beta"}},
+	}
+	t.Cleanup(func() {
+		config.History, config.APIKeys = savedHistory, savedKeys
+		history.Close()
+	})
+
+	// An hour on alpha, then ten minutes on beta where the player is still online
+	start := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	player := []config.Player{{Name: "Alice", PID: "1111", SID: "76561198000000001"}}
+	history.Record("alpha", player, start)
+	history.Record("alpha", player, start.Add(time.Hour))
+	history.Record("alpha", nil, start.Add(time.Hour+time.Minute))
+	history.Record("beta", player, start.Add(2*time.Hour))
+	history.Record("beta", player, start.Add(2*time.Hour+10*time.Minute))
+
+	tests := []struct {
+		key       string
+		playtime  int64
+		sessions  int
+		firstSeen time.Time
+		servers   int
+	}{
+		{"all-key", 70 * 60, 2, start, 2},
+		{"beta-key", 10 * 60, 1, start.Add(2 * time.Hour), 1},
+	}
+	handler := routes.RequireRole(config.RoleViewer, routes.PlayerHandler)
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodGet, "/players/76561198000000001", nil)
+		request.SetPathValue("steamid", "76561198000000001")
+		request.Header.Set("X-API-Key", test.key)
+		recorder := httptest.NewRecorder()
+		handler(recorder, request)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", test.key, recorder.Code, recorder.Body)
+		}
+
+		var got config.PlayerHistory
+		if err := json.Unmarshal(recorder.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Playtime != test.playtime || got.Sessions != test.sessions || !got.FirstSeen.Equal(test.firstSeen) {
+			t.Errorf("%s: playtime %d, sessions %d, first seen %v, want %d, %d, %v",
+				test.key, got.Playtime, got.Sessions, got.FirstSeen, test.playtime, test.sessions, test.firstSeen)
+		}
+		if got.Server != "beta" || !got.Online || len(got.Servers) != test.servers {
+			t.Errorf("%s: server %q, online %v with %d servers, want beta, online with %d",
+				test.key, got.Server, got.Online, len(got.Servers), test.servers)
+		}
+	}
+}
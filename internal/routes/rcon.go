@@ -1,94 +1,166 @@
 package routes
 
 import (
-    "encoding/json"
+    "context"
     "log"
     "net/http"
     "palworld-query-api/internal/config"
+    "strconv"
+    "time"
 )
 
-func RconHandler(w http.ResponseWriter, r *http.Request) {
-    routeRcon := config.Routes.Rcon
-    path := r.URL.Path
-    servers, err := config.GetConfig()
-
-    if path == routeRcon {
-        if err != nil {
-            http.Error(w, "Failed to read server configurations", http.StatusInternalServerError)
-            log.Println("Failed to read server configurations:", err)
-            return
-        }
+// ServersHandler serves the status of every server the API key can see, optionally
+// only those with every ?tag= given and in the ?group=.
+func ServersHandler(w http.ResponseWriter, r *http.Request) {
+    configured, err := config.GetConfig()
+    if err != nil {
+        writeConfigError(w, err)
+        return
+    }
 
-        log.Printf("Received API request: %s\n", path)
+    log.Printf("Received API request: %s\n", r.URL.Path)
 
-        serverDataMap, err := getAllRconData(servers)
-        if err != nil {
-            http.Error(w, "Error getting all server data", http.StatusInternalServerError)
-            log.Println("Error getting all server data:", err)
-            return
+    // Only answer for the servers the API key can see and the filters select
+    servers := make(map[string]config.ConfigServer, len(configured))
+    for name, server := range configured {
+        if canAccess(r, name) && matchesMeta(r, server) {
+            servers[name] = server
         }
+    }
 
-        // Encode and send the response
-        w.Header().Set("Content-Type", "application/json")
-        if err := json.NewEncoder(w).Encode(serverDataMap); err != nil {
-            http.Error(w, "Error encoding server data", http.StatusInternalServerError)
-            log.Println("Error encoding server data:", err)
-            return
-        }
-        log.Println("Sent all server data to client")
-        return
+    serverDataMap, updatedAt := getAllServerData(r, servers)
+    for name, info := range serverDataMap {
+        serverDataMap[name] = servedInfo(servers[name], info)
     }
 
-    // Extract server name from path
-    serverName := path[len(routeRcon):]
+    // Encode and send the response
+    setCacheHeaders(w, updatedAt)
+    writeJSON(w, http.StatusOK, serverDataMap)
+    log.Println("Sent all server data to client")
+}
+
+// ServerHandler serves the status of the server named in the path.
+func ServerHandler(w http.ResponseWriter, r *http.Request) {
+    serverName := r.PathValue("name")
 
     // Validate if the serverName exists in the servers map
-    serverData, ok := servers[serverName]
-    if !ok {
-        // Server does not exist
-        emptyResponse := map[string]interface{}{"message": "Server does not exist"}
-        w.Header().Set("Content-Type", "application/json")
-        if err := json.NewEncoder(w).Encode(emptyResponse); err != nil {
-            http.Error(w, "Error encoding server data", http.StatusInternalServerError)
-            log.Println("Error encoding server data:", err)
-            return
-        }
-        log.Printf("Server %s does not exist", serverName)
+    serverData, err := config.GetServerConfig(serverName)
+    if err != nil {
+        writeServerLookupError(w, serverName, err)
         return
     }
 
     // Get server data by name
-    serverDataInfo, err := config.GetRconData(serverData)
-    if err != nil {
-        log.Printf("Error getting server data for %s: %v\n", serverName, err)
-        // Return empty JSON object indicating that the server does not exist
-        emptyResponse := map[string]interface{}{"message": "Server data retrieval error"}
-        w.Header().Set("Content-Type", "application/json")
-        if err := json.NewEncoder(w).Encode(emptyResponse); err != nil {
-            http.Error(w, "Error encoding server data", http.StatusInternalServerError)
-            log.Println("Error encoding server data:", err)
-        }
+    snapshot := getServerData(r, serverName, serverData)
+    info := servedInfo(serverData, snapshot.Info)
+    setCacheHeaders(w, snapshot.UpdatedAt)
+    w.Header().Set("X-Circuit-Breaker", info.Breaker.State)
+
+    // A server that could not be queried at all is an error, a partial answer is not
+    if !info.Online && info.ErrorCode != "" {
+        log.Printf("Error getting server data for %s: %s\n", serverName, info.Error)
+        writeJSON(w, serverErrorStatus(info.ErrorCode), errorResponse{
+            Code:      info.ErrorCode,
+            Message:   info.Error,
+            Server:    serverName,
+            Retryable: config.IsRetryableCode(info.ErrorCode),
+        })
         return
     }
 
     // Encode and send the response
-    w.Header().Set("Content-Type", "application/json")
-    if err := json.NewEncoder(w).Encode(serverDataInfo); err != nil {
-        http.Error(w, "Error encoding server data", http.StatusInternalServerError)
-        log.Println("Error encoding server data:", err)
-        return
-    }
+    writeJSON(w, http.StatusOK, info)
     log.Printf("Sent server data for %s to client", serverName)
 }
 
-func getAllRconData(servers map[string]config.ConfigServer) (map[string]interface{}, error) {
-    serverDataMap := make(map[string]interface{})
-    for name := range servers {
-        serverDataInfo, err := config.GetRconData(servers[name])
-        if err != nil {
-            return nil, err
+// servedInfo returns a copy of the server info with the server's circuit breaker state
+// and its metadata from rcon.yaml.
+func servedInfo(server config.ConfigServer, info *config.ServerInfo) *config.ServerInfo {
+    served := *info
+    breaker := config.Breakers.State(server.Name)
+    served.Breaker = &breaker
+    served.Meta = server.Meta()
+    return &served
+}
+
+// matchesMeta reports whether the server has every ?tag= of the request and is in its ?group=.
+func matchesMeta(r *http.Request, server config.ConfigServer) bool {
+    query := r.URL.Query()
+    for _, tag := range query["tag"] {
+        if !server.HasTag(tag) {
+            return false
         }
-        serverDataMap[name] = serverDataInfo
     }
-    return serverDataMap, nil
+    if group := query.Get("group"); group != "" && !server.InGroup(group) {
+        return false
+    }
+    return true
+}
+
+// wantsFresh reports whether the client asked to bypass the snapshot cache with ?fresh=1.
+func wantsFresh(r *http.Request) bool {
+    fresh, _ := strconv.ParseBool(r.URL.Query().Get("fresh"))
+    return fresh
+}
+
+// getServerData serves the named server from the snapshot cache, querying it live
+// when there is no snapshot yet or the client asked for a fresh one.
+func getServerData(r *http.Request, serverName string, serverData config.ConfigServer) config.Snapshot {
+    if !wantsFresh(r) {
+        if snapshot, ok := config.Snapshots.Get(serverName); ok {
+            return snapshot
+        }
+    }
+
+    serverDataInfo, err := config.GetRconData(serverData)
+    if err != nil {
+        serverDataInfo = config.OfflineInfo(err)
+    }
+    return config.Snapshots.Set(serverName, serverDataInfo)
+}
+
+// getAllServerData serves every server from the snapshot cache and queries the
+// ones without a snapshot live. It also returns the time of the oldest snapshot.
+func getAllServerData(r *http.Request, servers map[string]config.ConfigServer) (map[string]*config.ServerInfo, time.Time) {
+    fresh := wantsFresh(r)
+    serverDataMap := make(map[string]*config.ServerInfo, len(servers))
+    missing := make(map[string]config.ConfigServer)
+    var oldest time.Time
+
+    for name, serverData := range servers {
+        snapshot, ok := config.Snapshots.Get(name)
+        if fresh || !ok {
+            missing[name] = serverData
+            continue
+        }
+        serverDataMap[name] = snapshot.Info
+        oldest = olderOf(oldest, snapshot.UpdatedAt)
+    }
+
+    if len(missing) > 0 {
+        ctx, cancel := context.WithTimeout(r.Context(), config.Config.RconDeadline)
+        defer cancel()
+        for name, serverDataInfo := range config.GetAllRconData(ctx, missing) {
+            snapshot := config.Snapshots.Set(name, serverDataInfo)
+            serverDataMap[name] = snapshot.Info
+            oldest = olderOf(oldest, snapshot.UpdatedAt)
+        }
+    }
+    return serverDataMap, oldest
+}
+
+func olderOf(a, b time.Time) time.Time {
+    if a.IsZero() || b.Before(a) {
+        return b
+    }
+    return a
+}
+
+// setCacheHeaders tells the client how old the served data is.
+func setCacheHeaders(w http.ResponseWriter, updatedAt time.Time) {
+    if updatedAt.IsZero() {
+        return
+    }
+    w.Header().Set("Last-Modified", updatedAt.UTC().Format(http.TimeFormat))
+    w.Header().Set("Age", strconv.Itoa(int(time.Since(updatedAt).Seconds())))
 }
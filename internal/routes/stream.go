@@ -0,0 +1,236 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"palworld-query-api/internal/config"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Live stream constants
+var StreamConfig = struct {
+	Heartbeat    time.Duration
+	WriteTimeout time.Duration
+}{
+	Heartbeat:    15 * time.Second,
+	WriteTimeout: 10 * time.Second,
+}
+
+var upgrader = websocket.Upgrader{
+	// Status pages are usually served from another origin
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// eventWriter sends one event to a stream client.
+type eventWriter interface {
+	writeEvent(event config.Event) error
+	writeHeartbeat() error
+}
+
+// StreamHandler streams status changes and player events of one server.
+func StreamHandler(w http.ResponseWriter, r *http.Request) {
+	serverName := r.PathValue("name")
+	if _, err := config.GetServerConfig(serverName); err != nil {
+		writeServerLookupError(w, serverName, err)
+		return
+	}
+	stream(w, r, serverName)
+}
+
+// StreamAllHandler streams status changes and player events of every server.
+func StreamAllHandler(w http.ResponseWriter, r *http.Request) {
+	stream(w, r, "")
+}
+
+// stream serves Server-Sent Events, or WebSocket messages when the client asks for an upgrade.
+// Player events newer than the client's last event id are replayed first, followed by the
+// current status of the streamed servers.
+func stream(w http.ResponseWriter, r *http.Request, serverName string) {
+	lastEventID, err := parseUintParam(lastEventIDOf(r))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Last-Event-ID must be an event id")
+		return
+	}
+
+	// Subscribe before reading the backlog so nothing falls in between
+	sub, err := config.Stream.Subscribe(serverName)
+	if errors.Is(err, config.ErrTooManySubscribers) {
+		writeError(w, http.StatusServiceUnavailable, "Too many stream subscribers")
+		return
+	}
+	defer config.Stream.Unsubscribe(sub)
+
+	var writer eventWriter
+	done := r.Context().Done()
+	if websocket.IsWebSocketUpgrade(r) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Println("Error upgrading stream to websocket:", err)
+			return
+		}
+		defer conn.Close()
+		writer = &wsWriter{conn: conn}
+		done = wsClosed(conn)
+	} else {
+		sse, err := newSSEWriter(w)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writer = sse
+	}
+	log.Printf("Stream opened for %q", serverName)
+	defer log.Printf("Stream closed for %q", serverName)
+
+	var backlog []config.Event
+	if lastEventID > 0 {
+		if serverName == "" {
+			backlog = config.Events.RecentAll(lastEventID)
+		} else {
+			backlog = config.Events.Recent(serverName, lastEventID, 0)
+		}
+	}
+	for _, event := range backlog {
+		if !canAccess(r, event.Server) {
+			continue
+		}
+		if err := writer.writeEvent(event); err != nil {
+			return
+		}
+		lastEventID = event.ID
+	}
+	for _, event := range currentStatus(serverName) {
+		if !canAccess(r, event.Server) {
+			continue
+		}
+		if err := writer.writeEvent(event); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(StreamConfig.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case event, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			// Skip what the backlog already covered and servers the key can't see
+			if event.ID <= lastEventID || !canAccess(r, event.Server) {
+				continue
+			}
+			if err := writer.writeEvent(event); err != nil {
+				return
+			}
+			lastEventID = event.ID
+		case <-heartbeat.C:
+			if err := writer.writeHeartbeat(); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// currentStatus turns the cached snapshots into status events without an id,
+// so they don't move the client's resume position.
+func currentStatus(serverName string) []config.Event {
+	events := []config.Event{}
+	for name, snapshot := range config.Snapshots.All() {
+		if serverName != "" && name != serverName {
+			continue
+		}
+		events = append(events, config.Event{Type: config.EventStatus, Server: name, Time: snapshot.UpdatedAt, Info: snapshot.Info})
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Server < events[j].Server })
+	return events
+}
+
+// lastEventIDOf reads the resume position from the Last-Event-ID header, or from the
+// query for clients that cannot set headers.
+func lastEventIDOf(r *http.Request) string {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return strings.TrimSpace(id)
+	}
+	return r.URL.Query().Get("lastEventId")
+}
+
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newSSEWriter(w http.ResponseWriter) (*sseWriter, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("streaming is not supported")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return &sseWriter{w: w, flusher: flusher}, nil
+}
+
+func (s *sseWriter) writeEvent(event config.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if event.ID > 0 {
+		if _, err := fmt.Fprintf(s.w, "id: %d\n", event.ID); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+func (s *sseWriter) writeHeartbeat() error {
+	if _, err := fmt.Fprint(s.w, ": heartbeat\n\n"); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+type wsWriter struct {
+	conn *websocket.Conn
+}
+
+func (ws *wsWriter) writeEvent(event config.Event) error {
+	ws.conn.SetWriteDeadline(time.Now().Add(StreamConfig.WriteTimeout))
+	return ws.conn.WriteJSON(event)
+}
+
+func (ws *wsWriter) writeHeartbeat() error {
+	return ws.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(StreamConfig.WriteTimeout))
+}
+
+// wsClosed reads (and discards) client messages until the connection closes,
+// which also lets gorilla answer pings and close frames.
+func wsClosed(conn *websocket.Conn) <-chan struct{} {
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+	return closed
+}
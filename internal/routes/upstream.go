@@ -0,0 +1,135 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"palworld-query-api/internal/config"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// upstreamClient searches the public server list API. Results are cached per query for
+// ApiConfig.CacheTTL, and concurrent searches for the same query share one upstream search.
+type upstreamClient struct {
+	client *http.Client
+	group  singleflight.Group
+	mu     sync.Mutex
+	cache  map[string]upstreamEntry
+}
+
+type upstreamEntry struct {
+	servers []Server
+	expires time.Time
+}
+
+var upstream = &upstreamClient{client: &http.Client{}, cache: make(map[string]upstreamEntry)}
+
+// search returns every server matching query, and whether it came from the cache.
+func (c *upstreamClient) search(query string) ([]Server, bool, error) {
+	if servers, ok := c.cached(query); ok {
+		return servers, true, nil
+	}
+
+	result, err, _ := c.group.Do(query, func() (interface{}, error) {
+		// A search that finished while this one waited for the group is as good as ours
+		if servers, ok := c.cached(query); ok {
+			return servers, nil
+		}
+		servers, err := c.fetchAll(query)
+		if err != nil {
+			return nil, err
+		}
+		c.store(query, servers)
+		return servers, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return result.([]Server), false, nil
+}
+
+func (c *upstreamClient) cached(query string) ([]Server, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.cache[query]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.servers, true
+}
+
+func (c *upstreamClient) store(query string, servers []Server) {
+	ttl := config.ApiConfig.CacheTTL
+	if ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for key, entry := range c.cache {
+		if now.After(entry.expires) {
+			delete(c.cache, key)
+		}
+	}
+	c.cache[query] = upstreamEntry{servers: servers, expires: now.Add(ttl)}
+}
+
+// fetchAll follows next_page_url until the last page or ApiConfig.MaxPages.
+func (c *upstreamClient) fetchAll(query string) ([]Server, error) {
+	pageURL := config.ApiConfig.Base + config.ApiConfig.Search + "?q=" + url.QueryEscape(query)
+	servers := []Server{}
+	for page := 1; ; page++ {
+		response, err := c.fetchPage(pageURL)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, response.ServerList...)
+		if !response.IsNextPage || response.NextPageURL == "" {
+			return servers, nil
+		}
+		if maxPages := config.ApiConfig.MaxPages; maxPages > 0 && page >= maxPages {
+			log.Printf("Server list search for %q has more than %d pages, keeping the first %d servers", query, maxPages, len(servers))
+			return servers, nil
+		}
+
+		// The next page URL may be relative to the API base
+		pageURL = response.NextPageURL
+		if !strings.HasPrefix(pageURL, "http://") && !strings.HasPrefix(pageURL, "https://") {
+			pageURL = config.ApiConfig.Base + pageURL
+		}
+	}
+}
+
+func (c *upstreamClient) fetchPage(pageURL string) (*ServerListResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.ApiConfig.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	response, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		text, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return nil, fmt.Errorf("server list API answered %s: %s", response.Status, strings.TrimSpace(string(text)))
+	}
+	var page ServerListResponse
+	if err := json.NewDecoder(response.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("decoding server list API response: %w", err)
+	}
+	return &page, nil
+}